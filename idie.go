@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"idie/report"
 	"idie/requester"
 	"idie/threadman"
 	"idie/util"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
@@ -17,6 +22,10 @@ import (
 
 const (
 	updateTimeInterval = 500 * time.Millisecond
+
+	// hosts checked by a single discovery, every host of it is probed at the same time except by nmap
+	discoveryBatchHosts     = 16
+	nmapDiscoveryBatchHosts = 256
 )
 
 // ENUM optionOutputType
@@ -37,30 +46,81 @@ var (
 	appFlex       *tview.Flex
 
 	thread     = threadman.NewThreadman(threadman.WithWorkerLimit(optionWorkerLimit))
-	resultsMap = make(map[string]map[string][]int)
-	totalTask  = 0
+	resultsMap = make(map[string]*report.HostResults)
+	totalTask  = 0 // a task scans a batch of (ip, port)
+	totalPort  = 0 // count of (ip, port) to scan
+
+	processedTaskCounter atomic.Uint64
+	retryTaskCounter     atomic.Uint64 // tasks requeued to retry inconclusive ports, on top of totalTask
+	processedPortCounter atomic.Uint64
+	errorPortCounter     atomic.Uint64
+
+	hostnamesMap  = make(map[string][]string) // ip address to its hostname targets
+	resolveErrors []*util.ResolveError
+	liveHostsMap  map[string]string // ip address of up host to the reason, nil if discovery is skipped
+	// failures of the discovery, the targets which fail are scanned anyway
+	discoveryErrors []error
 
 	startingTime = time.Now()
+	endingTime   time.Time
 
 	// options & args
-	argStartIP        = "" // ipv4 only
-	argEndIP          = "" // ipv4 only
-	optionPort        = "" // format: 80,443,8080
-	optionOutputType  = "" // format: json,txt,csv
-	optionOutputFile  = "" // output file path
-	optionWorkerLimit = 10 // worker for running task
+	argTargets            = []string{}           // format: 10.0.0.1, 10.0.0.0/24, 10.0.0.1-10.0.0.50, 10.0.1-3.1-254, 2001:db8::/120
+	optionPort            = ""                   // format: 80-90,443,!85
+	optionOutputType      = ""                   // format: json,txt,csv
+	optionOutputFile      = ""                   // output file path
+	optionWorkerLimit     = 10                   // worker for running task
+	optionTargetsFile     = ""                   // file path, one target per line
+	optionExclude         = ""                   // format: 10.0.0.1,10.0.0.0/24
+	optionExcludeFile     = ""                   // file path, one target per line
+	optionResolver        = ""                   // format: 1.1.1.1 or 1.1.1.1:53, empty for system resolver
+	optionScanner         = ""                   // tcp scanner, see requester.ScannerNames()
+	optionUdpScanner      = ""                   // udp scanner, empty for the pair of optionScanner
+	optionProto           = ""                   // format: tcp,udp
+	optionUdp             = false                // same as adding udp to optionProto
+	optionBanners         = false                // grab banner of open tcp port
+	optionTls             = false                // collect tls certificate of open tcp port
+	optionTlsExpiry       = 30                   // days, certificate expires within it is reported
+	optionHttp            = false                // fingerprint http(s) service of open tcp port
+	optionService         = false                // detect service and version of open port
+	optionRetries         = 0                    // retries of inconclusive probe (timeout, error)
+	optionRetryBackoff    = 1 * time.Second      // wait before the first retry, doubled on every retry
+	optionRetryBackoffMax = 30 * time.Second     // longest wait before a retry
+	optionRate            = 0.0                  // probes per second of every worker together, 0 for no limit
+	optionHostRate        = 0.0                  // probes per second to a host, 0 for no limit
+	optionSubnetRate      = 0.0                  // probes per second to a subnet, 0 for no limit
+	optionSubnetPrefix    = 24                   // ipv4 subnet of optionSubnetRate
+	optionSubnet6Prefix   = 64                   // ipv6 subnet of optionSubnetRate
+	optionSkipDiscovery   = false                // scan every target without checking if it's up
+	optionDiscovery       = ""                   // format: tcp,icmp,nmap, empty for the default of the scanner
+	optionDiscoveryPorts  = "80,443,22,445,3389" // ports of tcp discovery
+	optionBatchHosts      = 0                    // ip addresses in a task, 0 for scanner default
+	optionRandomize       = false                // scan (ip address, port) in random order
+	optionSeed            = int64(0)             // seed of optionRandomize, 0 for time based
+	optionBatchPorts      = 0                    // ports in a task, 0 for scanner default
+	optionTimeout         = 3 * time.Second      // time to wait for a response of a single probe
+	optionHostTimeout     = 5 * time.Minute      // time to wait for every probe of a host, 0 for no limit
 
 	// processed options & args
-	optionPortProcessed []int
-	optionOutputFilePtr *os.File
+	optionPortProcessed     []int
+	argTargetsProcessed     []util.TargetRange
+	optionScannersProcessed []requester.Scanner // a scanner for each protocol to scan
+	optionProbesProcessed   []requester.Probe   // run against every open port
+
+	optionDiscoveryProcessed      []string
+	optionDiscoveryPortsProcessed []int
+	discoveryRequester            *requester.Requester
+	optionExcludeProcessed        []util.TargetRange
+	optionOutputFilePtr           *os.File
 )
 
 func getThreadStat() string {
 	idleCounter := thread.GetStandByCounter()
 	runningCounter := thread.GetRunningCounter()
 	doneCounter := thread.GetDoneCounter()
-	totalCounter := totalTask
-	return fmt.Sprintf(" idle:%d running:%d done:%d total:%d ", idleCounter, runningCounter, doneCounter, totalCounter)
+	totalCounter := uint64(totalTask) + retryTaskCounter.Load()
+	// errors are ports in error state, panics are tasks which panic (every port of them is an error too)
+	return fmt.Sprintf(" idle:%d running:%d done:%d total:%d ports:%d/%d errors:%d panics:%d retries:%d ", idleCounter, runningCounter, doneCounter, totalCounter, processedPortCounter.Load(), totalPort, errorPortCounter.Load(), thread.GetErrorCounter(), retryTaskCounter.Load())
 }
 
 func elapsedTime() string {
@@ -72,7 +132,8 @@ func updateStat() {
 	for {
 		time.Sleep(updateTimeInterval)
 
-		if thread.GetDoneCounter() == uint64(totalTask) {
+		// every result is processed, not only done
+		if processedTaskCounter.Load() == uint64(totalTask)+retryTaskCounter.Load() {
 			app.Stop()
 			return
 		}
@@ -90,8 +151,8 @@ func threadUpdateListener() {
 		case <-threadman.ThreadInactiveNotifier:
 			return
 		case task, _ := <-threadman.TaskDoneNotifier:
+			resultWg.Add(1)
 			go func(tParam *threadman.Task) {
-				resultWg.Add(1)
 				resultMutex.Lock()
 				defer resultMutex.Unlock()
 				defer resultWg.Done()
@@ -102,181 +163,629 @@ func threadUpdateListener() {
 	}
 }
 
-func tcpUdpMapToString(showOpen bool, showClosed bool) (str string) {
+func resultsMapToString(showOpen bool, showClosed bool) (str string) {
 	results := [][]string{
-		{"IP Address", "Open", "Closed"},
+		{"IP Address", "Open", "Open|Filtered", "Closed", "Filtered", "Error", "Unknown"},
 	}
+	hasError, hasUnknown := false, false
 
-	longestFirstColumn := len("IP Address") // ip column and its value str length
-	longestSecondColumn := len("Open")      // open port column and its value str length
-	longestThirdColumn := len("Closed")     // closed port column and its value str length
+	doc := buildReport()
+	for _, host := range doc.Hosts {
+		ipText := host.IP
+		if len(host.Hostnames) > 0 {
+			ipText += " (" + strings.Join(host.Hostnames, ",") + ")"
+		}
 
-	for ip := range resultsMap {
-		if len(ip) > longestFirstColumn {
-			longestFirstColumn = len(ip)
+		results = append(results, []string{
+			ipText,
+			portsToString(host.Tcp.Open, host.Udp.Open),
+			portsToString(host.Tcp.OpenFiltered, host.Udp.OpenFiltered),
+			portsToString(host.Tcp.Closed, host.Udp.Closed),
+			portsToString(host.Tcp.Filtered, host.Udp.Filtered),
+			portsToString(host.Tcp.Error, host.Udp.Error),
+			portsToString(host.Tcp.Unknown, host.Udp.Unknown),
+		})
+
+		hasError = hasError || len(host.Tcp.Error)+len(host.Udp.Error) > 0
+		hasUnknown = hasUnknown || len(host.Tcp.Unknown)+len(host.Udp.Unknown) > 0
+	}
+
+	// longest str length of every column
+	longestColumns := make([]int, len(results[0]))
+	for _, result := range results {
+		for i, column := range result {
+			if len(column) > longestColumns[i] {
+				longestColumns[i] = len(column)
+			}
 		}
+	}
 
-		_, tcps, udps, err := breakTcpUdpMap(ip)
-		if err != nil {
-			panic(err)
+	// add spacing with ' ' rune calculated from (longest column + 2)
+	// open|filtered is mostly for udp, error and unknown are shown only if there is any
+	showOpenFiltered := showOpen && isProtocolScanned(requester.PROTOCOL_UDP)
+	showColumns := []bool{true, showOpen, showOpenFiltered, showClosed, showClosed, hasError, hasUnknown}
+	for _, result := range results {
+		for i, column := range result {
+			if showColumns[i] {
+				str += util.FillPostfixWithRune(column, longestColumns[i]+2, ' ')
+			}
 		}
 
-		openText := ""
-		var openPorts []int
-		for i, port := range tcps {
-			openPorts = append(openPorts, port)
+		str += "\n"
+	}
 
-			openText += fmt.Sprintf("%d", port)
-			if i < len(tcps)-1 {
-				openText += ","
+	// banner of every port which has it
+	bannerText := ""
+	for _, host := range doc.Hosts {
+		for _, port := range host.Ports {
+			if port.Banner != "" {
+				bannerText += net.JoinHostPort(host.IP, strconv.Itoa(port.Port)) + "/" + port.Protocol + "  " + port.Banner + "\n"
 			}
 		}
-		for i, port := range udps {
-			openPorts = append(openPorts, port)
+	}
+	if bannerText != "" {
+		str += "\nBanners\n" + bannerText
+	}
+
+	// detected service of every port which has it
+	serviceText := ""
+	for _, host := range doc.Hosts {
+		for _, port := range host.Ports {
+			if port.Product == "" {
+				continue
+			}
 
-			openText += fmt.Sprintf("%d", port)
-			if i < len(udps)-1 {
-				openText += ","
+			serviceText += net.JoinHostPort(host.IP, strconv.Itoa(port.Port)) + "/" + port.Protocol + "  " + port.Service + "  " + strings.TrimSpace(port.Product+" "+port.Version)
+			if port.ExtraInfo != "" {
+				serviceText += " (" + port.ExtraInfo + ")"
 			}
+			serviceText += "\n"
 		}
+	}
+	if serviceText != "" {
+		str += "\nServices\n" + serviceText
+	}
 
-		closedText := ""
-		for i, port := range optionPortProcessed {
-			if util.IsIntSliceContains(openPorts, port) {
+	// http fingerprint of every port which speaks http
+	httpText := ""
+	for _, host := range doc.Hosts {
+		for _, port := range host.Ports {
+			if port.Http == nil {
 				continue
 			}
 
-			closedText += fmt.Sprintf("%d", port)
-			if i < len(optionPortProcessed)-1 {
-				closedText += ","
+			httpText += fmt.Sprintf("%s  %d  %s  %q", port.Http.Url, port.Http.StatusCode, port.Http.Server, port.Http.Title)
+			if port.Http.Location != "" {
+				httpText += "  -> " + port.Http.Location
 			}
+			httpText += "  sha256:" + port.Http.ContentHash + "\n"
 		}
+	}
+	if httpText != "" {
+		str += "\nHTTP\n" + httpText
+	}
 
-		if len(openText) > longestSecondColumn {
-			longestSecondColumn = len(openText)
+	// latency of every host which answers
+	latencyText := ""
+	for _, host := range doc.Hosts {
+		if host.Latency.Samples == 0 {
+			continue
 		}
-		if len(closedText) > longestThirdColumn {
-			longestThirdColumn = len(closedText)
+		latencyText += fmt.Sprintf("%s  min %.3fms  avg %.3fms  p95 %.3fms  (%d ports)\n", host.IP, host.Latency.MinMs, host.Latency.AvgMs, host.Latency.P95Ms, host.Latency.Samples)
+	}
+	if latencyText != "" {
+		str += "\nLatency\n" + latencyText
+	}
+
+	if doc.LiveHosts != nil {
+		str += fmt.Sprintf("\nLive hosts (%d)\n", len(doc.LiveHosts))
+		for _, liveHost := range doc.LiveHosts {
+			ipText := liveHost.IP
+			if len(liveHost.Hostnames) > 0 {
+				ipText += " (" + strings.Join(liveHost.Hostnames, ",") + ")"
+			}
+			str += ipText + "  " + liveHost.Reason + "\n"
 		}
+	}
 
-		results = append(results, []string{ip, openText, closedText})
+	if len(doc.ExpiringCerts) > 0 {
+		str += fmt.Sprintf("\nExpiring certificates (within %d days)\n", optionTlsExpiry)
+		for _, cert := range doc.ExpiringCerts {
+			str += fmt.Sprintf("%s  %s (%d days left)  %s\n", net.JoinHostPort(cert.IP, strconv.Itoa(cert.Port)), cert.NotAfter.Format(time.DateOnly), cert.DaysLeft, cert.Subject)
+		}
 	}
 
-	// add spacing with ' ' rune calculated from (longest column + 2)
-	longestFirstColumn += 2
-	longestSecondColumn += 2
-	longestThirdColumn += 2
-	for _, result := range results {
-		str += util.FillPostfixWithRune(result[0], longestFirstColumn, ' ')
+	if len(doc.DiscoveryErrors) > 0 {
+		str += "\nDiscovery errors (scanned anyway)\n" + strings.Join(doc.DiscoveryErrors, "\n") + "\n"
+	}
 
-		if showOpen {
-			str += util.FillPostfixWithRune(result[1], longestSecondColumn, ' ')
+	if len(doc.Unresolved) > 0 {
+		str += "\nUnresolved\n"
+		for _, unresolved := range doc.Unresolved {
+			str += unresolved.Hostname + "  " + unresolved.Error + "\n"
 		}
+	}
+
+	return
+}
+
+// udp port is suffixed with /udp, tcp port is not to keep it short
+func portsToString(tcpPorts []int, udpPorts []int) string {
+	return strings.Trim(intSliceToString(tcpPorts, "")+","+intSliceToString(udpPorts, "/udp"), ",")
+}
+
+func intSliceToString(slice []int, suffix string) string {
+	var items []string
+	for _, item := range slice {
+		items = append(items, strconv.Itoa(item)+suffix)
+	}
+	return strings.Join(items, ",")
+}
 
-		if showClosed {
-			str += util.FillPostfixWithRune(result[2], longestThirdColumn, ' ')
+func printToFile() error {
+	str := resultsMapToString(true, true)
+	return util.WriteStringToFile(optionOutputFilePtr, str)
+}
+
+func buildReport() *report.Document {
+	doc := report.NewDocument(startingTime, endingTime, optionPortProcessed, optionWorkerLimit)
+
+	var ips []string
+	for ip := range resultsMap {
+		ips = append(ips, ip)
+	}
+	util.SortIPs(ips)
+
+	for _, ip := range ips {
+		doc.Hosts = append(doc.Hosts, report.NewHost(ip, hostnamesMap[ip], resultsMap[ip]))
+	}
+
+	if liveHostsMap != nil {
+		var liveIps []string
+		for ip := range liveHostsMap {
+			liveIps = append(liveIps, ip)
 		}
+		util.SortIPs(liveIps)
 
-		str += "\n"
+		doc.LiveHosts = []report.LiveHost{}
+		for _, ip := range liveIps {
+			doc.LiveHosts = append(doc.LiveHosts, report.NewLiveHost(ip, hostnamesMap[ip], liveHostsMap[ip]))
+		}
 	}
 
-	return
+	if optionRandomize {
+		doc.Seed = &optionSeed
+	}
+
+	if optionTls {
+		doc.ExpiringCerts = report.NewExpiringCerts(doc.Hosts, endingTime, optionTlsExpiry)
+	}
+
+	for _, discoveryError := range discoveryErrors {
+		doc.DiscoveryErrors = append(doc.DiscoveryErrors, discoveryError.Error())
+	}
+
+	for _, resolveError := range resolveErrors {
+		doc.Unresolved = append(doc.Unresolved, report.Unresolved{
+			Hostname: resolveError.Hostname,
+			Error:    resolveError.Err.Error(),
+		})
+	}
+
+	return doc
 }
 
-func printToFile() {
-	str := tcpUdpMapToString(true, true)
-	util.WriteStringToFile(optionOutputFilePtr, str)
+func printJsonToFile() error {
+	return report.WriteJSON(optionOutputFilePtr, buildReport())
 }
 
-func breakTcpUdpMap(ip string) (tcpUdpMap map[string][]int, tcps []int, udps []int, err error) {
-	var ok bool
+func printCsvToFile() error {
+	return report.WriteCSV(optionOutputFilePtr, buildReport())
+}
 
-	tcpUdpMap, ok = resultsMap[ip]
-	if !ok {
-		err = fmt.Errorf("Error getting tcpUdpMap for ip %s", ip)
-		return
+func processTaskDone(task *threadman.Task) {
+	results, ok := task.Result.([]*requester.ScanResult)
+	batch, isBatch := task.Data.(*scanBatch)
+
+	// task panic, every port of its batch is reported as error
+	if task.Err != nil && isBatch {
+		results = requester.NewErrorResults(batch.targets, batch.ports, batch.scanner.Protocol(), task.Err)
+		ok = true
 	}
 
-	tcps, ok = tcpUdpMap["tcp"]
 	if !ok {
-		err = fmt.Errorf("Error getting tcps for ip %s", ip)
+		processedTaskCounter.Add(1)
 		return
 	}
 
-	udps, ok = tcpUdpMap["udp"]
-	if !ok {
-		err = fmt.Errorf("Error getting udps for ip %s", ip)
-		return
+	if isBatch {
+		results = retryInconclusive(batch, results)
 	}
 
-	return
+	for _, result := range results {
+		hostResults, ok := resultsMap[result.IP]
+		if !ok {
+			hostResults = report.NewHostResults()
+			resultsMap[result.IP] = hostResults
+		}
+		hostResults.Add(result)
+		if result.State == requester.PORT_STATE_ERROR {
+			errorPortCounter.Add(1)
+		}
+	}
+
+	processedPortCounter.Add(uint64(len(results)))
+	processedTaskCounter.Add(1)
 }
 
-func processTaskDone(task *threadman.Task) {
-	result, ok := task.Result.(string)
-	if !ok {
-		return
+// inconclusive result is retried, unless the scanner decides itself (see requester.RetryScanner)
+func isRetryable(scanner requester.Scanner, result *requester.ScanResult) bool {
+	if retryScanner, ok := scanner.(requester.RetryScanner); ok {
+		return retryScanner.IsRetryable(result)
 	}
+	return result.IsInconclusive()
+}
 
-	// ip,port,tcpBool,udpBool
-	resultSplitted := util.Explode(result, ",")
-	ip := resultSplitted[0]
-	port, _ := strconv.Atoi(resultSplitted[1])
-	tcpBool, _ := strconv.ParseBool(resultSplitted[2])
-	udpBool, _ := strconv.ParseBool(resultSplitted[3])
+// retryInconclusive requeues inconclusive ports of the batch (timeout, error) as a new task per target,
+// the other results are returned, their state is final
+func retryInconclusive(batch *scanBatch, results []*requester.ScanResult) []*requester.ScanResult {
+	var finalResults []*requester.ScanResult
+	retryResults := make(map[string][]*requester.ScanResult) // ip address to its inconclusive results
 
-	if _, ok = resultsMap[ip]; !ok {
-		resultsMap[ip] = make(map[string][]int)
-		resultsMap[ip]["tcp"] = []int{}
-		resultsMap[ip]["udp"] = []int{}
+	for _, result := range results {
+		result.Attempts = batch.attempt
+		if batch.attempt <= optionRetries && isRetryable(batch.scanner, result) {
+			retryResults[result.IP] = append(retryResults[result.IP], result)
+			continue
+		}
+		finalResults = append(finalResults, result)
 	}
 
-	_, tcps, udps, err := breakTcpUdpMap(ip)
-	if err != nil {
-		return
+	for _, target := range batch.targets {
+		var ports []int
+		for _, result := range retryResults[target.IP] {
+			ports = append(ports, result.Port)
+		}
+		if len(ports) == 0 {
+			continue
+		}
+
+		retryBatch := &scanBatch{scanner: batch.scanner, targets: []requester.Target{target}, ports: ports, attempt: batch.attempt + 1}
+		retryTaskCounter.Add(1)
+		if !thread.Requeue(newScanTask(retryBatch), retryBackoff(batch.attempt)) {
+			// stopped, the last attempt is final
+			retryTaskCounter.Add(^uint64(0))
+			finalResults = append(finalResults, retryResults[target.IP]...)
+		}
 	}
 
-	if tcpBool {
-		resultsMap[ip]["tcp"] = append(tcps, port)
-		resultsMap[ip]["tcp"] = util.UniqueIntSlice(resultsMap[ip]["tcp"])
+	return finalResults
+}
+
+// backoff before the next attempt, doubled on every attempt up to --retry-backoff-max
+func retryBackoff(attempt int) time.Duration {
+	backoff := optionRetryBackoff
+	for i := 1; i < attempt && backoff < optionRetryBackoffMax; i++ {
+		backoff *= 2
 	}
 
-	if udpBool {
-		resultsMap[ip]["udp"] = append(udps, port)
-		resultsMap[ip]["udp"] = util.UniqueIntSlice(resultsMap[ip]["udp"])
+	return min(backoff, optionRetryBackoffMax)
+}
+
+func isProtocolScanned(protocol string) bool {
+	for _, scanner := range optionScannersProcessed {
+		if scanner.Protocol() == protocol {
+			return true
+		}
 	}
+	return false
+}
+
+func isNmapScanner(scanner string) bool {
+	return scanner == requester.SCANNER_SYN || scanner == requester.SCANNER_NMAP_CONNECT || scanner == requester.SCANNER_NMAP_UDP
+}
+
+// probeRateLimiter limits probes of --rate, --host-rate and --subnet-rate, shared by every worker
+type probeRateLimiter struct {
+	global *threadman.TokenBucket      // nil for no limit
+	subnet *threadman.KeyedTokenBucket // nil for no limit
+	host   *threadman.KeyedTokenBucket // nil for no limit
 }
 
-func wrapperExecutorTask(ip string, port int) interface{} {
-	req := requester.NewRequester()
-	ipScan, portScan, isOpen, protocol, _ := req.NmapSyn(ip, port)
-	isTcpOpen, isUdpOpen := false, false
-	if protocol == "tcp" {
-		isTcpOpen = isOpen
+// newProbeRateLimiter returns nil if there is no rate limit
+func newProbeRateLimiter() *probeRateLimiter {
+	if optionRate == 0 && optionHostRate == 0 && optionSubnetRate == 0 {
+		return nil
 	}
-	if protocol == "udp" {
-		isUdpOpen = isOpen
+
+	limiter := &probeRateLimiter{}
+	if optionRate > 0 {
+		limiter.global = threadman.NewTokenBucket(optionRate, 1)
+	}
+	if optionSubnetRate > 0 {
+		limiter.subnet = threadman.NewKeyedTokenBucket(optionSubnetRate, 1)
+	}
+	if optionHostRate > 0 {
+		limiter.host = threadman.NewKeyedTokenBucket(optionHostRate, 1)
 	}
-	return interface{}(fmt.Sprintf("%s,%d,%t,%t", ipScan, portScan, isTcpOpen, isUdpOpen))
+
+	return limiter
 }
 
-func createDiscovery(start string, end string, ports []int) {
-	generatedIPs, err := util.GenerateIPRange(start, end)
+func (l *probeRateLimiter) Wait(ctx context.Context, ip string) error {
+	if l.global != nil {
+		if err := l.global.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l.subnet != nil {
+		if err := l.subnet.Wait(ctx, subnetOf(ip)); err != nil {
+			return err
+		}
+	}
+	if l.host != nil {
+		if err := l.host.Wait(ctx, ip); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subnet of ip address by --subnet-prefix or --subnet6-prefix (e.g. 10.0.0.0/24)
+func subnetOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		mask := net.CIDRMask(optionSubnetPrefix, 32)
+		return (&net.IPNet{IP: ipv4.Mask(mask), Mask: mask}).String()
+	}
+
+	mask := net.CIDRMask(optionSubnet6Prefix, 128)
+	return (&net.IPNet{IP: parsed.Mask(mask), Mask: mask}).String()
+}
+
+// max rate of a single nmap process, --rate is split between workers since each of them runs its own nmap
+func nmapMaxRate() float64 {
+	rate := optionHostRate
+	if optionRate > 0 {
+		workerRate := optionRate / float64(optionWorkerLimit)
+		if rate == 0 || workerRate < rate {
+			rate = workerRate
+		}
+	}
+
+	return rate
+}
+
+// udp scanner which is used along with the tcp scanner when --udp-scanner is not set
+func udpScannerOf(tcpScanner string) string {
+	if isNmapScanner(tcpScanner) {
+		return requester.SCANNER_NMAP_UDP
+	}
+	return requester.SCANNER_UDP
+}
+
+// discovery methods when --discovery is not set, nmap is used if the scanner needs it anyway,
+// icmp is used only if it's permitted
+func defaultDiscoveryMethods() []string {
+	if isNmapScanner(optionScanner) {
+		return []string{requester.DISCOVERY_NMAP}
+	}
+
+	methods := []string{requester.DISCOVERY_TCP}
+	if requester.IsIcmpPermitted() {
+		methods = append(methods, requester.DISCOVERY_ICMP)
+	}
+	return methods
+}
+
+// scanBatch is the input of a task, kept in Task.Data to report or retry it
+type scanBatch struct {
+	scanner requester.Scanner
+	targets []requester.Target
+	ports   []int
+	attempt int // starts from 1
+}
+
+func newScanTask(batch *scanBatch) *threadman.Task {
+	return &threadman.Task{
+		Func: func() interface{} {
+			return wrapperExecutorTask(batch.scanner, batch.targets, batch.ports)
+		},
+		Data: batch,
+	}
+}
+
+func wrapperExecutorTask(scanner requester.Scanner, targets []requester.Target, ports []int) interface{} {
+	results, err := scanner.Scan(context.Background(), targets, ports)
 	if err != nil {
-		return
+		results = requester.NewErrorResults(targets, ports, scanner.Protocol(), err)
+	}
+
+	requester.RunProbes(context.Background(), results, optionProbesProcessed)
+
+	return interface{}(results)
+}
+
+// batch size of the scanner, overridden by --batch-hosts and --batch-ports
+func scannerBatchSize(scanner requester.Scanner) (hostBatch int, portBatch int) {
+	hostBatch, portBatch = 1, 1
+	if batchScanner, ok := scanner.(requester.BatchScanner); ok {
+		hostBatch, portBatch = batchScanner.BatchSize()
+	}
+
+	if optionBatchHosts > 0 {
+		hostBatch = optionBatchHosts
+	}
+	if optionBatchPorts > 0 {
+		portBatch = optionBatchPorts
+	}
+
+	return
+}
+
+// resolve hostname targets by --worker at the same time, unresolved hostname is reported and skipped,
+// but unresolved exclude is fatal
+func resolveTargets(targets []util.TargetRange, excludes []util.TargetRange) {
+	resolver := util.NewResolver(optionResolver)
+
+	resolveErrors = util.ResolveTargetRanges(targets, resolver, optionWorkerLimit)
+	for _, resolveError := range resolveErrors {
+		fmt.Println(resolveError.Error())
+	}
+
+	// unresolved excluded hostname may still be a target (e.g. inside a cidr), so nothing is scanned
+	if excludeErrors := util.ResolveTargetRanges(excludes, resolver, optionWorkerLimit); len(excludeErrors) > 0 {
+		fmt.Printf("Invalid exclude (--exclude, --exclude-file): %v\n", excludeErrors[0])
+		os.Exit(1)
+	}
+
+	hostnamesMap = util.TargetRangesHostnames(targets)
+}
+
+// check which targets are up, the up ones are returned as targets to scan
+func discoverHosts(targets []util.TargetRange, excludes []util.TargetRange) []util.TargetRange {
+	fmt.Printf("Discovering hosts (%s)...\n", strings.Join(optionDiscoveryProcessed, ","))
+
+	hostBatch := discoveryBatchHosts
+	if util.IsStringSliceContains(optionDiscoveryProcessed, requester.DISCOVERY_NMAP) {
+		hostBatch = nmapDiscoveryBatchHosts
+	}
+	iterator := util.NewHostIterator(targets, excludes, hostBatch)
+	if optionRandomize {
+		iterator.Randomize(uint64(optionSeed))
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	liveHostsMap = make(map[string]string)
+
+	for i := 0; i < optionWorkerLimit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				ips, _, ok := iterator.Next()
+				if !ok {
+					return
+				}
+
+				var lTargets []requester.Target
+				for _, ip := range ips {
+					lTargets = append(lTargets, requester.Target{IP: ip.String()})
+				}
+
+				up, err := discoveryRequester.DiscoverHosts(context.Background(), lTargets, optionDiscoveryProcessed, optionDiscoveryPortsProcessed)
+
+				mutex.Lock()
+				for ip, reason := range up {
+					liveHostsMap[ip] = reason
+				}
+				discoveryErrors = append(discoveryErrors, unwrapErrors(err)...)
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(discoveryErrors) > 0 {
+		fmt.Printf("Discovery failed %d time(s), the failed hosts are scanned anyway: %v\n", len(discoveryErrors), discoveryErrors[0])
+	}
+
+	var ips []string
+	for ip := range liveHostsMap {
+		ips = append(ips, ip)
+	}
+	util.SortIPs(ips)
+
+	// a single range of every live host, so checking if an ip address is in it does not scan every live host
+	var liveIPs []net.IP
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			liveIPs = append(liveIPs, parsed)
+		}
+	}
+	fmt.Printf("Found %d live host(s)\n", len(liveIPs))
+
+	return []util.TargetRange{util.NewIPListRange("live hosts", liveIPs)}
+}
+
+// errors joined by errors.Join one by one, nil for nil
+func unwrapErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func createDiscovery(targets []util.TargetRange, excludes []util.TargetRange, ports []int) {
+	resolveTargets(targets, excludes)
+
+	if !optionSkipDiscovery {
+		targets = discoverHosts(targets, excludes)
 	}
 
-	for _, ip := range generatedIPs {
-		for _, port := range ports {
-			lIp := ip
-			lPort := port
-			thread.AddTask(func() interface{} {
-				return wrapperExecutorTask(lIp, lPort)
-			})
+	// every protocol is scanned by its own scanner, one after another
+	var iterators []*util.ScanIterator
+	var excludeCounter uint64
+	for _, scanner := range optionScannersProcessed {
+		hostBatch, portBatch := scannerBatchSize(scanner)
+		iterator := util.NewScanIterator(targets, excludes, ports, hostBatch, portBatch)
+		if optionRandomize {
+			iterator.Randomize(uint64(optionSeed))
 		}
+
+		hostCounter, excluded, batchCounter := iterator.Count()
+		totalTask += int(batchCounter)
+		totalPort += int(hostCounter) * len(ports)
+		excludeCounter = excluded
+
+		iterators = append(iterators, iterator)
+	}
+	thread.AddStandByCounter(uint64(totalTask))
+
+	if excludeCounter > 0 {
+		fmt.Printf("Excluded %d host(s)\n", excludeCounter)
+	}
+	if optionRandomize {
+		fmt.Printf("Randomized order (--seed %d)\n", optionSeed)
 	}
 
-	totalTask = len(generatedIPs) * len(ports)
+	// task is created on demand, only when there is a free worker
+	current := 0
+	thread.SetTaskSource(func() (*threadman.Task, bool) {
+		var ips []net.IP
+		var ports []int
+		ok := false
+		for ; current < len(iterators); current++ {
+			if ips, ports, ok = iterators[current].Next(); ok {
+				break
+			}
+		}
+		if !ok {
+			return nil, false
+		}
+
+		var lTargets []requester.Target
+		for _, ip := range ips {
+			target := requester.Target{IP: ip.String()}
+			if hostnames := hostnamesMap[target.IP]; len(hostnames) > 0 {
+				target.Hostname = hostnames[0]
+			}
+			lTargets = append(lTargets, target)
+		}
+		batch := &scanBatch{scanner: optionScannersProcessed[current], targets: lTargets, ports: ports, attempt: 1}
+
+		return newScanTask(batch), true
+	})
 }
 
 func drawStatus(screen tcell.Screen, x int, y int, width int, height int) (int, int, int, int) {
@@ -284,7 +793,10 @@ func drawStatus(screen tcell.Screen, x int, y int, width int, height int) (int,
 
 	centerY := y + height/2
 	centerY += 2
-	progress := float64(thread.GetDoneCounter()) / float64(totalTask)
+	progress := 0.0
+	if totalPort > 0 {
+		progress = float64(processedPortCounter.Load()) / float64(totalPort)
+	}
 	progressWidth := int(float64(width) * progress)
 	for cx := x + 1; cx < x+progressWidth-1; cx++ {
 		screen.SetContent(cx, centerY, '█', nil, tcell.StyleDefault.Foreground(tcell.ColorGreen))
@@ -297,33 +809,95 @@ func drawStatus(screen tcell.Screen, x int, y int, width int, height int) (int,
 }
 
 func prepareFlag() {
-	flag.StringVar(&optionPort, "port", "80", "Port to check (format: 80-90,443,25565)")
+	flag.StringVar(&optionPort, "port", "80", "Port to check, prefix with ! to exclude (format: 80-90,443,25565,!85)")
 	flag.StringVar(&optionOutputType, "type", "txt", "Output type (json,txt,csv)")
 	flag.StringVar(&optionOutputFile, "file", "", "Output file path")
 	flag.IntVar(&optionWorkerLimit, "worker", 10, "Worker limit")
+	flag.StringVar(&optionTargetsFile, "targets-file", "", "Read targets from file, one target per line (# for comment)")
+	flag.StringVar(&optionExclude, "exclude", "", "Targets to exclude (format: 10.0.0.1,10.0.0.0/24)")
+	flag.StringVar(&optionExcludeFile, "exclude-file", "", "Read targets to exclude from file, one target per line (# for comment)")
+	flag.StringVar(&optionScanner, "scanner", requester.SCANNER_SYN, "TCP scanner backend ("+strings.Join(requester.ScannerNames(), ",")+")")
+	flag.StringVar(&optionUdpScanner, "udp-scanner", "", "UDP scanner backend, default is nmap-udp for nmap tcp scanner and udp for the others")
+	flag.StringVar(&optionProto, "proto", requester.PROTOCOL_TCP, "Protocols to scan (format: tcp,udp)")
+	flag.BoolVar(&optionUdp, "udp", false, "Scan udp ports too, same as adding udp to --proto")
+	flag.IntVar(&optionRetries, "retries", 0, "Retries of a probe which times out or fails")
+	flag.DurationVar(&optionRetryBackoff, "retry-backoff", 1*time.Second, "Wait before the first retry, doubled on every retry (e.g. 500ms, 1s)")
+	flag.DurationVar(&optionRetryBackoffMax, "retry-backoff-max", 30*time.Second, "Longest wait before a retry")
+	flag.Float64Var(&optionRate, "rate", 0, "Probes (packets or connections) per second of every worker together, 0 for no limit")
+	flag.Float64Var(&optionHostRate, "host-rate", 0, "Probes per second to a single host, 0 for no limit")
+	flag.Float64Var(&optionSubnetRate, "subnet-rate", 0, "Probes per second to a single subnet, 0 for no limit (not supported by nmap scanners and nmap discovery)")
+	flag.IntVar(&optionSubnetPrefix, "subnet-prefix", 24, "Prefix length of ipv4 subnet of --subnet-rate")
+	flag.IntVar(&optionSubnet6Prefix, "subnet6-prefix", 64, "Prefix length of ipv6 subnet of --subnet-rate")
+	flag.BoolVar(&optionSkipDiscovery, "skip-discovery", false, "Scan every target without checking if it's up first")
+	flag.StringVar(&optionDiscovery, "discovery", "", "Host discovery methods (format: tcp,icmp,nmap), default is nmap for nmap scanners and tcp,icmp for the others")
+	flag.StringVar(&optionDiscoveryPorts, "discovery-ports", intSliceToString(requester.DiscoveryTcpPorts, ""), "Ports of tcp host discovery")
+	flag.BoolVar(&optionBanners, "banners", false, "Grab banner of open tcp ports")
+	flag.BoolVar(&optionTls, "tls", false, "Collect tls certificate of open tcp ports")
+	flag.IntVar(&optionTlsExpiry, "tls-expiry-days", 30, "Report tls certificates which expire within the days")
+	flag.BoolVar(&optionService, "service-detect", false, "Detect service and version of open ports (nmap -sV for nmap scanners, built-in fingerprints for the others)")
+	flag.BoolVar(&optionHttp, "http", false, "Fingerprint http(s) services of open tcp ports (status, server, title, redirect, content hash)")
+	flag.IntVar(&optionBatchHosts, "batch-hosts", 0, "Hosts scanned by a single task (e.g. a single nmap process), 0 for scanner default")
+	flag.BoolVar(&optionRandomize, "randomize", false, "Scan ip addresses and ports in random order instead of one host after another")
+	flag.Int64Var(&optionSeed, "seed", 0, "Seed of --randomize to reproduce the order, implies --randomize, 0 for time based")
+	flag.IntVar(&optionBatchPorts, "batch-ports", 0, "Ports scanned by a single task (e.g. a single nmap process), 0 for scanner default")
+	flag.DurationVar(&optionTimeout, "timeout", 3*time.Second, "Time to wait for a response of a single probe (e.g. 500ms, 3s)")
+	flag.DurationVar(&optionHostTimeout, "host-timeout", 5*time.Minute, "Time to wait for every probe of a host, 0 for no limit (e.g. 30s, 5m)")
+	flag.StringVar(&optionResolver, "resolver", "", "DNS server to resolve hostname targets (format: 1.1.1.1:53), default is system resolver")
 }
 
 func flagValidate() {
 	//args
-	args := flag.Args()
-	if len(args) < 2 {
-		fmt.Println("Usage: idie <start ip> <end ip>")
+	argTargets = flag.Args()
+	if len(argTargets) < 1 && optionTargetsFile == "" {
+		fmt.Println("Usage: idie [options] <target> [target...]")
+		fmt.Println("Target: 10.0.0.1, 10.0.0.0/24, 10.0.0.1-10.0.0.50, 10.0.1-3.1-254, 2001:db8::1, 2001:db8::/120, db.internal.example")
 		os.Exit(1)
 	}
 
-	argStartIP = args[0]
-	if !util.IsValidIPv4(argStartIP) {
-		fmt.Println("Invalid start ip")
+	var err error
+	argTargetsProcessed, err = util.ParseTargetSpecs(argTargets)
+	if err != nil {
+		fmt.Printf("Invalid target: %v\n", err)
+		os.Exit(1)
+	}
+
+	//option
+	if optionTargetsFile != "" {
+		targetsFromFile, err := util.ParseTargetFile(optionTargetsFile)
+		if err != nil {
+			fmt.Printf("Invalid targets file (--targets-file): %v\n", err)
+			os.Exit(1)
+		}
+		argTargetsProcessed = append(argTargetsProcessed, targetsFromFile...)
+	}
+
+	if len(argTargetsProcessed) < 1 {
+		fmt.Println("No target to scan")
 		os.Exit(1)
 	}
 
-	argEndIP = args[1]
-	if !util.IsValidIPv4(argEndIP) {
-		fmt.Println("Invalid end ip")
+	if err = util.CheckScanTargets(argTargetsProcessed); err != nil {
+		fmt.Printf("Invalid target: %v\n", err)
 		os.Exit(1)
 	}
 
-	//option
+	if optionExclude != "" {
+		optionExcludeProcessed, err = util.ParseTargetSpecs(util.Explode(optionExclude, ","))
+		if err != nil {
+			fmt.Printf("Invalid exclude (--exclude): %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if optionExcludeFile != "" {
+		excludesFromFile, err := util.ParseTargetFile(optionExcludeFile)
+		if err != nil {
+			fmt.Printf("Invalid exclude file (--exclude-file): %v\n", err)
+			os.Exit(1)
+		}
+		optionExcludeProcessed = append(optionExcludeProcessed, excludesFromFile...)
+	}
+
 	if optionOutputFile == "" {
 		fmt.Println("Invalid output file (--file)")
 		os.Exit(1)
@@ -334,13 +908,171 @@ func flagValidate() {
 		os.Exit(1)
 	}
 
+	if optionTimeout <= 0 {
+		fmt.Println("Invalid timeout (--timeout)")
+		os.Exit(1)
+	}
+
+	if optionHostTimeout < 0 {
+		fmt.Println("Invalid host timeout (--host-timeout)")
+		os.Exit(1)
+	}
+
+	protocols := util.Explode(strings.ReplaceAll(optionProto, " ", ""), ",")
+	if optionUdp && !util.IsStringSliceContains(protocols, requester.PROTOCOL_UDP) {
+		protocols = append(protocols, requester.PROTOCOL_UDP)
+	}
+
+	if optionRate < 0 || optionHostRate < 0 || optionSubnetRate < 0 {
+		fmt.Println("Invalid rate (--rate, --host-rate, --subnet-rate)")
+		os.Exit(1)
+	}
+
+	if optionSubnetPrefix < 0 || optionSubnetPrefix > 32 || optionSubnet6Prefix < 0 || optionSubnet6Prefix > 128 {
+		fmt.Println("Invalid subnet prefix (--subnet-prefix, --subnet6-prefix)")
+		os.Exit(1)
+	}
+
+	scannerOptions := []requester.Option{
+		requester.WithTimeOut(optionTimeout),
+		requester.WithHostTimeOut(optionHostTimeout),
+		requester.WithServiceDetect(optionService),
+	}
+	if rateLimiter := newProbeRateLimiter(); rateLimiter != nil {
+		scannerOptions = append(scannerOptions, requester.WithRateLimiter(rateLimiter), requester.WithMaxRate(nmapMaxRate()))
+	}
+
+	var scannedProtocols []string
+	for _, protocol := range protocols {
+		if util.IsStringSliceContains(scannedProtocols, protocol) {
+			continue
+		}
+		scannedProtocols = append(scannedProtocols, protocol)
+
+		flagName, scannerName := "scanner", optionScanner
+		if protocol == requester.PROTOCOL_UDP {
+			flagName, scannerName = "udp-scanner", optionUdpScanner
+			if scannerName == "" {
+				scannerName = udpScannerOf(optionScanner)
+			}
+		} else if protocol != requester.PROTOCOL_TCP {
+			fmt.Printf("Invalid protocol (--proto): %q, expected tcp or udp\n", protocol)
+			os.Exit(1)
+		}
+
+		scanner, err := requester.NewScanner(scannerName, scannerOptions...)
+		if err != nil {
+			fmt.Printf("Invalid scanner (--%s): %v\n", flagName, err)
+			os.Exit(1)
+		}
+		if scanner.Protocol() != protocol {
+			fmt.Printf("Invalid scanner (--%s): %s is not a %s scanner\n", flagName, scannerName, protocol)
+			os.Exit(1)
+		}
+		// nmap paces its probes by --max-rate only, it has no limit per subnet
+		if isNmapScanner(scannerName) && optionSubnetRate > 0 {
+			fmt.Printf("Invalid scanner (--%s): %s does not support --subnet-rate, use connect or udp scanner\n", flagName, scannerName)
+			os.Exit(1)
+		}
+
+		optionScannersProcessed = append(optionScannersProcessed, scanner)
+	}
+
+	// probe name with its flag
+	probes := []struct {
+		enabled  bool
+		flagName string
+		name     string
+	}{
+		{optionBanners, "banners", requester.PROBE_BANNER},
+		{optionTls, "tls", requester.PROBE_TLS},
+		{optionHttp, "http", requester.PROBE_HTTP},                 // after tls, to know the scheme
+		{optionService, "service-detect", requester.PROBE_SERVICE}, // after the others, to reuse banner and http server
+	}
+	for _, probeOption := range probes {
+		if !probeOption.enabled {
+			continue
+		}
+
+		probe, err := requester.NewProbe(probeOption.name, scannerOptions...)
+		if err != nil {
+			fmt.Printf("Invalid probe (--%s): %v\n", probeOption.flagName, err)
+			os.Exit(1)
+		}
+		optionProbesProcessed = append(optionProbesProcessed, probe)
+	}
+
+	if !optionSkipDiscovery {
+		optionDiscoveryProcessed = defaultDiscoveryMethods()
+		if optionDiscovery != "" {
+			optionDiscoveryProcessed = util.Explode(strings.ReplaceAll(optionDiscovery, " ", ""), ",")
+		}
+
+		for _, method := range optionDiscoveryProcessed {
+			if method != requester.DISCOVERY_TCP && method != requester.DISCOVERY_ICMP && method != requester.DISCOVERY_NMAP {
+				fmt.Printf("Invalid discovery method (--discovery): %q, expected tcp, icmp or nmap\n", method)
+				os.Exit(1)
+			}
+			if method == requester.DISCOVERY_ICMP && !requester.IsIcmpPermitted() {
+				fmt.Println("Invalid discovery method (--discovery): icmp needs root privileges")
+				os.Exit(1)
+			}
+			// nmap paces its probes by --max-rate only, it has no limit per subnet
+			if method == requester.DISCOVERY_NMAP && optionSubnetRate > 0 {
+				fmt.Println("Invalid discovery method (--discovery): nmap does not support --subnet-rate, use tcp,icmp or --skip-discovery")
+				os.Exit(1)
+			}
+		}
+
+		optionDiscoveryPortsProcessed, err = util.ParsePortList(optionDiscoveryPorts)
+		if err != nil {
+			fmt.Printf("Invalid discovery port list (--discovery-ports): %v\n", err)
+			os.Exit(1)
+		}
+
+		discoveryRequester = requester.NewRequester(scannerOptions...)
+	}
+
+	if optionRetries < 0 {
+		fmt.Println("Invalid retries (--retries)")
+		os.Exit(1)
+	}
+
+	if optionRetryBackoff < 0 || optionRetryBackoffMax < optionRetryBackoff {
+		fmt.Println("Invalid retry backoff (--retry-backoff, --retry-backoff-max)")
+		os.Exit(1)
+	}
+
+	if optionTlsExpiry < 0 {
+		fmt.Println("Invalid tls expiry days (--tls-expiry-days)")
+		os.Exit(1)
+	}
+
+	if optionBatchHosts < 0 {
+		fmt.Println("Invalid batch hosts (--batch-hosts)")
+		os.Exit(1)
+	}
+
+	if optionBatchPorts < 0 {
+		fmt.Println("Invalid batch ports (--batch-ports)")
+		os.Exit(1)
+	}
+
+	if optionSeed != 0 {
+		optionRandomize = true
+	}
+	if optionRandomize && optionSeed == 0 {
+		optionSeed = time.Now().UnixNano()
+	}
+
 	if optionWorkerLimit <= 0 {
 		fmt.Println("Invalid worker limit (--worker)")
 		os.Exit(1)
 	}
 
-	if !util.IsValidPortList(optionPort) {
-		fmt.Println("Invalid port list (--port)")
+	optionPortProcessed, err = util.ParsePortList(optionPort)
+	if err != nil {
+		fmt.Printf("Invalid port list (--port): %v\n", err)
 		os.Exit(1)
 	}
 }
@@ -373,11 +1105,21 @@ func main() {
 	flag.Parse()
 	flagValidate()
 
-	optionOutputFilePtr = util.OpenFileOrCreate(optionOutputFile)
+	// txt output is appended, but json and csv document is not valid anymore if appended to another one
+	openOutputFile := util.OpenFileOrCreate
+	if optionOutputType == OUTPUT_TYPE_JSON || optionOutputType == OUTPUT_TYPE_CSV {
+		openOutputFile = util.TruncateOrCreate
+	}
+
+	var err error
+	optionOutputFilePtr, err = openOutputFile(optionOutputFile)
+	if err != nil {
+		fmt.Println("Unable to open output file (--file):", err)
+		os.Exit(1)
+	}
 
 	fmt.Println("Creating task...")
-	optionPortProcessed = util.ExplodeToIntSlice(optionPort, ",")
-	createDiscovery(argStartIP, argEndIP, optionPortProcessed)
+	createDiscovery(argTargetsProcessed, optionExcludeProcessed, optionPortProcessed)
 	threadOptimize()
 	fmt.Println("Starting thread...")
 
@@ -390,24 +1132,28 @@ func main() {
 	fmt.Println("Thread started")
 
 	if err := app.Run(); err != nil {
-		panic(err)
+		fmt.Println("Unable to run interface:", err)
+		os.Exit(1)
 	}
 
 	thread.Stop()
 	fmt.Println("Waiting for result...")
 	resultWg.Wait()
+	endingTime = time.Now()
 
 	// print result
 	if optionOutputType == OUTPUT_TYPE_TXT {
-		printToFile()
+		err = printToFile()
 	}
 	if optionOutputType == OUTPUT_TYPE_JSON {
-		// TODO
-		fmt.Println("JSON output not implemented yet")
+		err = printJsonToFile()
 	}
 	if optionOutputType == OUTPUT_TYPE_CSV {
-		// TODO
-		fmt.Println("CSV output not implemented yet")
+		err = printCsvToFile()
+	}
+	if err != nil {
+		fmt.Println("Unable to write result:", err)
+		os.Exit(1)
 	}
 
 	fmt.Println("Done")
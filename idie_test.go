@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		backoff    time.Duration
+		backoffMax time.Duration
+		attempt    int
+		want       time.Duration
+	}{
+		{"first", time.Second, 30 * time.Second, 1, time.Second},
+		{"second", time.Second, 30 * time.Second, 2, 2 * time.Second},
+		{"fifth", time.Second, 30 * time.Second, 5, 16 * time.Second},
+		{"capped", time.Second, 30 * time.Second, 6, 30 * time.Second},
+		{"capped without overflow", time.Second, 30 * time.Second, 1000, 30 * time.Second},
+		{"max below backoff", time.Second, 500 * time.Millisecond, 1, 500 * time.Millisecond},
+		{"no backoff", 0, 30 * time.Second, 3, 0},
+	}
+
+	backoff, backoffMax := optionRetryBackoff, optionRetryBackoffMax
+	t.Cleanup(func() {
+		optionRetryBackoff, optionRetryBackoffMax = backoff, backoffMax
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			optionRetryBackoff, optionRetryBackoffMax = test.backoff, test.backoffMax
+
+			if got := retryBackoff(test.attempt); got != test.want {
+				t.Errorf("retryBackoff(%d) with --retry-backoff %v --retry-backoff-max %v = %v, want %v", test.attempt, test.backoff, test.backoffMax, got, test.want)
+			}
+		})
+	}
+}
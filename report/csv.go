@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/csv"
+	"idie/requester"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keep the column order stable, append new columns at the end only
+var csvHeader = []string{
+	"ip", "port", "protocol", "state", "service", "reason", "rtt_ms", "error", "timestamp", "hostname", "banner",
+	"tls_subject", "tls_sans", "tls_issuer", "tls_serial", "tls_not_before", "tls_not_after", "tls_key_type", "tls_version", "tls_cipher",
+	"http_status", "http_server", "http_title", "http_location", "http_content_sha256",
+	"product", "version", "extra_info", "cpe",
+	"started_at", "duration_ms", "attempts",
+}
+
+// WriteCSV writes one row per (ip, port, protocol) of the document,
+// closed, filtered and open|filtered ports have no detail, see HostResults
+func WriteCSV(w io.Writer, doc *Document) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, host := range doc.Hosts {
+		for _, port := range hostPorts(host) {
+			record := []string{
+				host.IP,
+				strconv.Itoa(port.Port),
+				port.Protocol,
+				port.State,
+				port.Service,
+				port.Reason,
+				strconv.FormatFloat(port.RttMs, 'f', 3, 64),
+				port.Error,
+				formatTime(port.Timestamp, time.RFC3339),
+				strings.Join(host.Hostnames, " "),
+				port.Banner,
+			}
+			record = append(record, tlsRecord(port.Tls)...)
+			record = append(record, httpRecord(port.Http)...)
+			record = append(record, port.Product, port.Version, port.ExtraInfo, strings.Join(port.CPE, " "))
+			record = append(record, formatTime(port.StartedAt, time.RFC3339Nano), strconv.FormatFloat(port.DurationMs, 'f', 3, 64), attemptsRecord(port.Attempts))
+
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// hostPorts returns the ports with detail along with the ports which are listed by state only, sorted by port
+func hostPorts(host Host) []Port {
+	ports := append([]Port{}, host.Ports...)
+	for _, protocolStates := range []struct {
+		protocol string
+		states   PortStates
+	}{
+		{requester.PROTOCOL_TCP, host.Tcp},
+		{requester.PROTOCOL_UDP, host.Udp},
+	} {
+		for _, stateList := range []struct {
+			state requester.PortState
+			ports []int
+		}{
+			{requester.PORT_STATE_CLOSED, protocolStates.states.Closed},
+			{requester.PORT_STATE_FILTERED, protocolStates.states.Filtered},
+			{requester.PORT_STATE_OPEN_FILTERED, protocolStates.states.OpenFiltered},
+		} {
+			for _, port := range stateList.ports {
+				ports = append(ports, Port{Port: port, Protocol: protocolStates.protocol, State: string(stateList.state)})
+			}
+		}
+	}
+
+	sortPorts(ports)
+
+	return ports
+}
+
+// zero time is empty, e.g. time of the port which has no detail
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+func attemptsRecord(attempts int) string {
+	if attempts == 0 {
+		return ""
+	}
+	return strconv.Itoa(attempts)
+}
+
+// tls columns of the port, empty if the port does not speak tls
+func tlsRecord(tls *Tls) []string {
+	if tls == nil {
+		return make([]string, 9)
+	}
+
+	return []string{
+		tls.Subject,
+		strings.Join(tls.SANs, " "),
+		tls.Issuer,
+		tls.Serial,
+		tls.NotBefore.Format(time.RFC3339),
+		tls.NotAfter.Format(time.RFC3339),
+		tls.KeyType,
+		tls.Version,
+		tls.Cipher,
+	}
+}
+
+// http columns of the port, empty if the port does not speak http
+func httpRecord(http *Http) []string {
+	if http == nil {
+		return make([]string, 5)
+	}
+
+	return []string{
+		strconv.Itoa(http.StatusCode),
+		http.Server,
+		http.Title,
+		http.Location,
+		http.ContentHash,
+	}
+}
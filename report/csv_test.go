@@ -0,0 +1,79 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"idie/requester"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	open := newResult(80, requester.PROTOCOL_TCP, requester.PORT_STATE_OPEN)
+	open.Banner = "SSH-2.0-OpenSSH_9.6"
+
+	tests := []struct {
+		name string
+		doc  *Document
+		rows [][]string // ip, port, protocol, state, reason, hostname, banner, attempts of every row
+	}{
+		{"no host", NewDocument(time.Time{}, time.Time{}, nil, 1), nil},
+		{"no port", newTestDocument(), nil},
+		{
+			"sorted by port then protocol",
+			newTestDocument(
+				open,
+				newResult(53, requester.PROTOCOL_UDP, requester.PORT_STATE_OPEN_FILTERED),
+				newResult(53, requester.PROTOCOL_TCP, requester.PORT_STATE_CLOSED),
+				newResult(22, requester.PROTOCOL_TCP, requester.PORT_STATE_ERROR),
+			),
+			[][]string{
+				{"10.0.0.1", "22", "tcp", "error", "test", "host.example", "", "1"},
+				{"10.0.0.1", "53", "tcp", "closed", "", "host.example", "", ""},
+				{"10.0.0.1", "53", "udp", "open|filtered", "", "host.example", "", ""},
+				{"10.0.0.1", "80", "tcp", "open", "test", "host.example", "SSH-2.0-OpenSSH_9.6", "1"},
+			},
+		},
+	}
+
+	columns := []string{"ip", "port", "protocol", "state", "reason", "hostname", "banner", "attempts"}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := WriteCSV(&buffer, test.doc); err != nil {
+				t.Fatalf("WriteCSV error: %v", err)
+			}
+
+			records, err := csv.NewReader(&buffer).ReadAll()
+			if err != nil {
+				t.Fatalf("WriteCSV writes invalid csv: %v", err)
+			}
+			if !reflect.DeepEqual(records[0], csvHeader) {
+				t.Fatalf("header = %v, want %v", records[0], csvHeader)
+			}
+
+			var rows [][]string
+			for _, record := range records[1:] {
+				if len(record) != len(csvHeader) {
+					t.Fatalf("row has %d columns, want %d", len(record), len(csvHeader))
+				}
+
+				var row []string
+				for _, column := range columns {
+					for i, name := range csvHeader {
+						if name == column {
+							row = append(row, record[i])
+						}
+					}
+				}
+				rows = append(rows, row)
+			}
+
+			if !reflect.DeepEqual(rows, test.rows) {
+				t.Errorf("rows = %v, want %v", rows, test.rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"idie/requester"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newResult returns result of the port with the state, answered in a millisecond
+func newResult(port int, protocol string, state requester.PortState) *requester.ScanResult {
+	result := requester.NewScanResult("10.0.0.1", port, protocol)
+	result.State = state
+	result.Reason = "test"
+	result.RTT = time.Millisecond
+	return result
+}
+
+// newTestDocument returns document of 10.0.0.1 which has a port of every result
+func newTestDocument(results ...*requester.ScanResult) *Document {
+	doc := NewDocument(time.Unix(0, 0).UTC(), time.Unix(60, 0).UTC(), []int{22, 80}, 4)
+
+	hostResults := NewHostResults()
+	for _, result := range results {
+		hostResults.Add(result)
+	}
+	doc.Hosts = append(doc.Hosts, NewHost("10.0.0.1", []string{"host.example"}, hostResults))
+
+	return doc
+}
+
+func TestWriteJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *Document
+		path []string // keys to the value, index of array is a key too
+		want interface{}
+	}{
+		{"empty hosts", NewDocument(time.Time{}, time.Time{}, nil, 1), []string{"hosts"}, []interface{}{}},
+		{"empty ports", NewDocument(time.Time{}, time.Time{}, nil, 1), []string{"ports"}, []interface{}{}},
+		{"discovery skipped", NewDocument(time.Time{}, time.Time{}, nil, 1), []string{"live_hosts"}, nil},
+		{"not randomized", NewDocument(time.Time{}, time.Time{}, nil, 1), []string{"seed"}, nil},
+		{"workers", newTestDocument(), []string{"workers"}, 4.0},
+		{"hostname", newTestDocument(), []string{"hosts", "0", "hostnames", "0"}, "host.example"},
+		{"open tcp", newTestDocument(newResult(80, requester.PROTOCOL_TCP, requester.PORT_STATE_OPEN)), []string{"hosts", "0", "open_tcp", "0"}, 80.0},
+		{"open port detail", newTestDocument(newResult(80, requester.PROTOCOL_TCP, requester.PORT_STATE_OPEN)), []string{"hosts", "0", "ports", "0", "reason"}, "test"},
+		{"closed by state only", newTestDocument(newResult(22, requester.PROTOCOL_TCP, requester.PORT_STATE_CLOSED)), []string{"hosts", "0", "ports"}, []interface{}{}},
+		{"closed udp", newTestDocument(newResult(53, requester.PROTOCOL_UDP, requester.PORT_STATE_CLOSED)), []string{"hosts", "0", "udp", "closed", "0"}, 53.0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := WriteJSON(&buffer, test.doc); err != nil {
+				t.Fatalf("WriteJSON error: %v", err)
+			}
+
+			var value interface{}
+			if err := json.Unmarshal(buffer.Bytes(), &value); err != nil {
+				t.Fatalf("WriteJSON writes invalid json: %v", err)
+			}
+
+			for _, key := range test.path {
+				switch node := value.(type) {
+				case map[string]interface{}:
+					value = node[key]
+				case []interface{}:
+					index, err := strconv.Atoi(key)
+					if err != nil || index >= len(node) {
+						t.Fatalf("%v has no index %s", node, key)
+					}
+					value = node[index]
+				default:
+					t.Fatalf("%v has no key %s", node, key)
+				}
+			}
+
+			if !reflect.DeepEqual(value, test.want) {
+				t.Errorf("%v = %#v, want %#v", test.path, value, test.want)
+			}
+		})
+	}
+}
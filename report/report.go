@@ -0,0 +1,402 @@
+package report
+
+import (
+	"idie/requester"
+	"math"
+	"sort"
+	"time"
+)
+
+// Port is the result of a single scanned port
+type Port struct {
+	Port       int       `json:"port"`
+	Protocol   string    `json:"protocol"`
+	State      string    `json:"state"`
+	Service    string    `json:"service"`
+	Product    string    `json:"product,omitempty"`
+	Version    string    `json:"version,omitempty"`
+	ExtraInfo  string    `json:"extra_info,omitempty"`
+	CPE        []string  `json:"cpe,omitempty"`
+	Reason     string    `json:"reason"`
+	RttMs      float64   `json:"rtt_ms"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Banner     string    `json:"banner,omitempty"`
+	Tls        *Tls      `json:"tls,omitempty"`
+	Http       *Http     `json:"http,omitempty"`
+}
+
+// Http is the response of GET / of a port
+type Http struct {
+	Url         string `json:"url"`
+	StatusCode  int    `json:"status_code"`
+	Server      string `json:"server"`
+	Title       string `json:"title"`
+	Location    string `json:"location"`
+	ContentHash string `json:"content_sha256"`
+}
+
+// Tls is the tls connection and certificate of a port
+type Tls struct {
+	Version   string    `json:"version"`
+	Cipher    string    `json:"cipher"`
+	Subject   string    `json:"subject"`
+	SANs      []string  `json:"sans"`
+	Issuer    string    `json:"issuer"`
+	Serial    string    `json:"serial"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	KeyType   string    `json:"key_type"`
+}
+
+// ExpiringCert is a certificate which expires within the expiry days of the document
+type ExpiringCert struct {
+	IP        string    `json:"ip"`
+	Port      int       `json:"port"`
+	Hostnames []string  `json:"hostnames"`
+	Subject   string    `json:"subject"`
+	NotAfter  time.Time `json:"not_after"`
+	DaysLeft  int       `json:"days_left"` // negative if already expired
+}
+
+// PortStates is the ports of a protocol grouped by their state
+type PortStates struct {
+	Open         []int `json:"open"`
+	Closed       []int `json:"closed"`
+	Filtered     []int `json:"filtered"`
+	OpenFiltered []int `json:"open_filtered"` // no response, the port is open or filtered
+	Error        []int `json:"error"`
+	Unknown      []int `json:"unknown"` // e.g. host timeout, not reported by nmap
+}
+
+// Host is the result of a single scanned ip address
+type Host struct {
+	IP        string     `json:"ip"`
+	Hostnames []string   `json:"hostnames"`
+	OpenTcp   []int      `json:"open_tcp"` // same as Tcp.Open
+	OpenUdp   []int      `json:"open_udp"` // same as Udp.Open
+	Closed    []int      `json:"closed"`   // Tcp.Closed and Udp.Closed together
+	Tcp       PortStates `json:"tcp"`
+	Udp       PortStates `json:"udp"`
+	Latency   Latency    `json:"latency"`
+	Ports     []Port     `json:"ports"` // detail of the ports, except closed, filtered and open|filtered ones, see HostResults
+}
+
+// Latency is the summary of rtt of the answered ports of a host
+type Latency struct {
+	Samples int     `json:"samples"` // count of answered ports, the others are zero
+	MinMs   float64 `json:"min_ms"`
+	AvgMs   float64 `json:"avg_ms"`
+	P95Ms   float64 `json:"p95_ms"`
+}
+
+// LiveHost is an ip address which is found up by the host discovery
+type LiveHost struct {
+	IP        string   `json:"ip"`
+	Hostnames []string `json:"hostnames"`
+	Reason    string   `json:"reason"` // e.g. tcp-80, echo-reply, arp-response
+}
+
+func NewLiveHost(ip string, hostnames []string, reason string) LiveHost {
+	if hostnames == nil {
+		hostnames = []string{}
+	}
+
+	return LiveHost{
+		IP:        ip,
+		Hostnames: hostnames,
+		Reason:    reason,
+	}
+}
+
+// Unresolved is a hostname target which can not be resolved, thus not scanned
+type Unresolved struct {
+	Hostname string `json:"hostname"`
+	Error    string `json:"error"`
+}
+
+// Document is the whole scan result, written by the structured outputs (json, csv)
+type Document struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Ports     []int     `json:"ports"`
+	Workers   int       `json:"workers"`
+	Hosts     []Host    `json:"hosts"`
+
+	Unresolved []Unresolved `json:"unresolved"`
+
+	ExpiringCerts []ExpiringCert `json:"expiring_certs,omitempty"`
+	LiveHosts     []LiveHost     `json:"live_hosts"` // null if host discovery is skipped
+	// failures of host discovery, the failed hosts are in LiveHosts with reason discovery-error
+	DiscoveryErrors []string `json:"discovery_errors"`
+	Seed            *int64   `json:"seed"` // seed of the random order, null if it's not randomized
+}
+
+func NewDocument(startTime time.Time, endTime time.Time, ports []int, workers int) *Document {
+	if ports == nil {
+		ports = []int{}
+	}
+
+	return &Document{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Ports:     ports,
+		Workers:   workers,
+		Hosts:     []Host{},
+
+		Unresolved: []Unresolved{},
+
+		DiscoveryErrors: []string{},
+	}
+}
+
+func NewPortStates() PortStates {
+	return PortStates{
+		Open:         []int{},
+		Closed:       []int{},
+		Filtered:     []int{},
+		OpenFiltered: []int{},
+		Error:        []int{},
+		Unknown:      []int{},
+	}
+}
+
+// Add adds port to the list of its state
+func (s *PortStates) Add(port int, state requester.PortState) {
+	switch state {
+	case requester.PORT_STATE_OPEN:
+		s.Open = append(s.Open, port)
+	case requester.PORT_STATE_CLOSED:
+		s.Closed = append(s.Closed, port)
+	case requester.PORT_STATE_FILTERED:
+		s.Filtered = append(s.Filtered, port)
+	case requester.PORT_STATE_OPEN_FILTERED:
+		s.OpenFiltered = append(s.OpenFiltered, port)
+	case requester.PORT_STATE_ERROR:
+		s.Error = append(s.Error, port)
+	default:
+		s.Unknown = append(s.Unknown, port)
+	}
+}
+
+func (s *PortStates) sort() {
+	for _, ports := range [][]int{s.Open, s.Closed, s.Filtered, s.OpenFiltered, s.Error, s.Unknown} {
+		sort.Ints(ports)
+	}
+}
+
+// HostResults is the scan results of an ip address, a large scan has plenty of closed, filtered and open|filtered ports,
+// so only their port number is kept, the other ports keep their full result
+type HostResults struct {
+	results []*requester.ScanResult
+	tcp     compactPorts
+	udp     compactPorts
+	rtts    []time.Duration // rtt of the answered ports, for Latency
+}
+
+// compactPorts is the port numbers of the states which are kept without their result
+type compactPorts struct {
+	closed       []uint16
+	filtered     []uint16
+	openFiltered []uint16
+}
+
+func NewHostResults() *HostResults {
+	return &HostResults{}
+}
+
+// Add keeps result, only its port number if the state is closed, filtered or open|filtered
+func (h *HostResults) Add(result *requester.ScanResult) {
+	if result.IsAnswered() && result.RTT > 0 {
+		h.rtts = append(h.rtts, result.RTT)
+	}
+
+	ports := &h.tcp
+	if result.Protocol == requester.PROTOCOL_UDP {
+		ports = &h.udp
+	}
+
+	switch result.State {
+	case requester.PORT_STATE_CLOSED:
+		ports.closed = append(ports.closed, uint16(result.Port))
+	case requester.PORT_STATE_FILTERED:
+		ports.filtered = append(ports.filtered, uint16(result.Port))
+	case requester.PORT_STATE_OPEN_FILTERED:
+		ports.openFiltered = append(ports.openFiltered, uint16(result.Port))
+	default:
+		h.results = append(h.results, result)
+	}
+}
+
+// addTo adds the ports to the lists of their state
+func (c *compactPorts) addTo(s *PortStates) {
+	for _, port := range c.closed {
+		s.Closed = append(s.Closed, int(port))
+	}
+	for _, port := range c.filtered {
+		s.Filtered = append(s.Filtered, int(port))
+	}
+	for _, port := range c.openFiltered {
+		s.OpenFiltered = append(s.OpenFiltered, int(port))
+	}
+}
+
+// NewHost creates Host from scan results of the ip address, every port is listed by the state it's reported with
+func NewHost(ip string, hostnames []string, results *HostResults) Host {
+	if hostnames == nil {
+		hostnames = []string{}
+	}
+
+	host := Host{
+		IP:        ip,
+		Hostnames: hostnames,
+		Tcp:       NewPortStates(),
+		Udp:       NewPortStates(),
+		Ports:     []Port{},
+	}
+
+	for _, result := range results.results {
+		host.Ports = append(host.Ports, NewPort(result))
+
+		if result.Protocol == requester.PROTOCOL_UDP {
+			host.Udp.Add(result.Port, result.State)
+		} else {
+			host.Tcp.Add(result.Port, result.State)
+		}
+	}
+
+	results.tcp.addTo(&host.Tcp)
+	results.udp.addTo(&host.Udp)
+
+	host.Tcp.sort()
+	host.Udp.sort()
+	host.Latency = NewLatency(results.rtts)
+	host.OpenTcp = host.Tcp.Open
+	host.OpenUdp = host.Udp.Open
+	host.Closed = append(append([]int{}, host.Tcp.Closed...), host.Udp.Closed...)
+	sort.Ints(host.Closed)
+
+	sortPorts(host.Ports)
+
+	return host
+}
+
+// sortPorts sorts by port, then tcp before udp
+func sortPorts(ports []Port) {
+	sort.SliceStable(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+}
+
+func NewPort(result *requester.ScanResult) Port {
+	port := Port{
+		Port:       result.Port,
+		Protocol:   result.Protocol,
+		State:      string(result.State),
+		Service:    result.Service,
+		Product:    result.Product,
+		Version:    result.Version,
+		ExtraInfo:  result.ExtraInfo,
+		CPE:        result.CPE,
+		Reason:     result.Reason,
+		RttMs:      durationToMs(result.RTT),
+		StartedAt:  result.StartedAt,
+		DurationMs: durationToMs(result.Duration),
+		Attempts:   result.Attempts,
+		Timestamp:  result.Timestamp,
+		Banner:     result.Banner,
+	}
+
+	if result.Err != nil {
+		port.Error = result.Err.Error()
+	}
+
+	if result.Tls != nil {
+		port.Tls = &Tls{
+			Version:   result.Tls.Version,
+			Cipher:    result.Tls.Cipher,
+			Subject:   result.Tls.Subject,
+			SANs:      result.Tls.SANs,
+			Issuer:    result.Tls.Issuer,
+			Serial:    result.Tls.Serial,
+			NotBefore: result.Tls.NotBefore,
+			NotAfter:  result.Tls.NotAfter,
+			KeyType:   result.Tls.KeyType,
+		}
+	}
+
+	if result.Http != nil {
+		port.Http = &Http{
+			Url:         result.Http.Url,
+			StatusCode:  result.Http.StatusCode,
+			Server:      result.Http.Server,
+			Title:       result.Http.Title,
+			Location:    result.Http.Location,
+			ContentHash: result.Http.ContentHash,
+		}
+	}
+
+	return port
+}
+
+// NewExpiringCerts returns certificates of the hosts which expire within days from now, the soonest first
+func NewExpiringCerts(hosts []Host, now time.Time, days int) []ExpiringCert {
+	certs := []ExpiringCert{}
+	deadline := now.AddDate(0, 0, days)
+
+	for _, host := range hosts {
+		for _, port := range host.Ports {
+			if port.Tls == nil || port.Tls.NotAfter.After(deadline) {
+				continue
+			}
+
+			certs = append(certs, ExpiringCert{
+				IP:        host.IP,
+				Port:      port.Port,
+				Hostnames: host.Hostnames,
+				Subject:   port.Tls.Subject,
+				NotAfter:  port.Tls.NotAfter,
+				DaysLeft:  int(math.Floor(port.Tls.NotAfter.Sub(now).Hours() / 24)),
+			})
+		}
+	}
+
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+
+	return certs
+}
+
+// NewLatency summarizes rtt of the answered ports, p95 is the nearest rank, rtts is sorted in place
+func NewLatency(rtts []time.Duration) Latency {
+	latency := Latency{Samples: len(rtts)}
+	if len(rtts) == 0 {
+		return latency
+	}
+
+	sort.Slice(rtts, func(i, j int) bool {
+		return rtts[i] < rtts[j]
+	})
+
+	var sum time.Duration
+	for _, rtt := range rtts {
+		sum += rtt
+	}
+
+	latency.MinMs = durationToMs(rtts[0])
+	latency.AvgMs = durationToMs(sum / time.Duration(len(rtts)))
+	latency.P95Ms = durationToMs(rtts[int(math.Ceil(0.95*float64(len(rtts))))-1])
+
+	return latency
+}
+
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
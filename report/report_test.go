@@ -0,0 +1,72 @@
+package report
+
+import (
+	"idie/requester"
+	"testing"
+	"time"
+)
+
+// millis returns durations of the milliseconds
+func millis(ms ...int) []time.Duration {
+	var durations []time.Duration
+	for _, m := range ms {
+		durations = append(durations, time.Duration(m)*time.Millisecond)
+	}
+	return durations
+}
+
+func TestNewLatency(t *testing.T) {
+	var hundred []int
+	for i := 100; i >= 1; i-- {
+		hundred = append(hundred, i)
+	}
+
+	tests := []struct {
+		name string
+		rtts []time.Duration
+		want Latency
+	}{
+		{"no answer", nil, Latency{}},
+		{"single", millis(7), Latency{Samples: 1, MinMs: 7, AvgMs: 7, P95Ms: 7}},
+		{"unsorted", millis(30, 10, 20), Latency{Samples: 3, MinMs: 10, AvgMs: 20, P95Ms: 30}},
+		// nearest rank of 95% of 20 is the 19th
+		{"twenty", millis(20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1), Latency{Samples: 20, MinMs: 1, AvgMs: 10.5, P95Ms: 19}},
+		{"hundred", millis(hundred...), Latency{Samples: 100, MinMs: 1, AvgMs: 50.5, P95Ms: 95}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := NewLatency(test.rtts); got != test.want {
+				t.Errorf("NewLatency() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHostResultsLatency(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   requester.PortState
+		rtt     time.Duration
+		samples int
+	}{
+		{"open", requester.PORT_STATE_OPEN, time.Millisecond, 1},
+		{"closed", requester.PORT_STATE_CLOSED, time.Millisecond, 1},
+		{"filtered", requester.PORT_STATE_FILTERED, 0, 0},
+		{"answered without rtt", requester.PORT_STATE_OPEN, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := newResult(80, requester.PROTOCOL_TCP, test.state)
+			result.RTT = test.rtt
+
+			hostResults := NewHostResults()
+			hostResults.Add(result)
+
+			if host := NewHost("10.0.0.1", nil, hostResults); host.Latency.Samples != test.samples {
+				t.Errorf("latency samples of %s port = %d, want %d", test.state, host.Latency.Samples, test.samples)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package requester
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// banner longer than this is truncated
+	bannerMaxSize = 512
+	// time to wait for a server which speaks first (ssh, smtp, ftp) before sending a nudge
+	bannerGreetingTimeOut = 2 * time.Second
+	// time to wait for the rest of the banner after its first bytes
+	bannerIdleTimeOut = 300 * time.Millisecond
+)
+
+// bannerProtocol is how to make a server on the port send its banner
+type bannerProtocol struct {
+	ports       []int
+	serverFirst bool   // server sends greeting right after connected
+	nudge       string // sent when nothing is received, %s is replaced by host:port
+}
+
+var (
+	bannerHttp = bannerProtocol{
+		ports: []int{80, 81, 3000, 5000, 8000, 8008, 8080, 8081, 8888, 9000},
+		nudge: "HEAD / HTTP/1.0\r\nHost: %s\r\nUser-Agent: idie\r\n\r\n",
+	}
+	bannerProtocols = []bannerProtocol{
+		bannerHttp,
+		{ports: []int{21}, serverFirst: true, nudge: "HELP\r\n"},                 // ftp
+		{ports: []int{22, 2222}, serverFirst: true, nudge: "SSH-2.0-idie\r\n"},   // ssh
+		{ports: []int{25, 587, 2525}, serverFirst: true, nudge: "EHLO idie\r\n"}, // smtp
+		{ports: []int{6379}, nudge: "INFO server\r\n"},                           // redis
+	}
+)
+
+type bannerProbe struct {
+	requester *Requester
+}
+
+func (p *bannerProbe) Probe(ctx context.Context, result *ScanResult) {
+	if result.Protocol != PROTOCOL_TCP {
+		return
+	}
+
+	banner, err := p.requester.GrabBanner(ctx, result.IP, result.Hostname, result.Port)
+	if err != nil {
+		return
+	}
+
+	result.Banner = banner
+}
+
+// GrabBanner connects to tcp port and reads its banner, the banner is sanitized to a single printable line
+// . server which speaks first (ssh, smtp, ftp) is given some time to send its greeting
+// . otherwise a nudge of the protocol of the port is sent, http request for unknown port
+// hostname is used as http host, ip is used if it's empty
+func (r *Requester) GrabBanner(ctx context.Context, ip string, hostname string, port int) (string, error) {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	if err := r.waitRate(ctx, ip); err != nil {
+		return "", err
+	}
+
+	dialer := net.Dialer{Timeout: r.TimeOut}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// close the connection when ctx is done, to stop blocking read
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	protocol, known := bannerProtocolOf(port)
+	if !known {
+		protocol = bannerProtocol{serverFirst: true, nudge: bannerHttp.nudge}
+	}
+
+	var banner []byte
+	if protocol.serverFirst {
+		banner = r.readBanner(conn, min(bannerGreetingTimeOut, r.TimeOut))
+	}
+
+	if len(banner) == 0 {
+		if hostname == "" {
+			hostname = ip
+		}
+		nudge := protocol.nudge
+		if strings.Contains(nudge, "%s") {
+			nudge = fmt.Sprintf(nudge, net.JoinHostPort(hostname, strconv.Itoa(port)))
+		}
+
+		_ = conn.SetWriteDeadline(time.Now().Add(r.TimeOut))
+		if _, err = conn.Write([]byte(nudge)); err != nil {
+			return "", err
+		}
+		banner = r.readBanner(conn, r.TimeOut)
+	}
+
+	if len(banner) == 0 {
+		return "", fmt.Errorf("no banner received")
+	}
+
+	return sanitizeBanner(banner), nil
+}
+
+// readBanner reads until bannerMaxSize, timeOut without any byte, or a short idle after the first bytes
+func (r *Requester) readBanner(conn net.Conn, timeOut time.Duration) []byte {
+	banner := make([]byte, 0, bannerMaxSize)
+	buffer := make([]byte, bannerMaxSize)
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeOut))
+	for len(banner) < bannerMaxSize {
+		n, err := conn.Read(buffer[:bannerMaxSize-len(banner)])
+		banner = append(banner, buffer[:n]...)
+		if err != nil {
+			break
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(bannerIdleTimeOut))
+	}
+
+	return banner
+}
+
+func bannerProtocolOf(port int) (bannerProtocol, bool) {
+	for _, protocol := range bannerProtocols {
+		for _, protocolPort := range protocol.ports {
+			if protocolPort == port {
+				return protocol, true
+			}
+		}
+	}
+
+	return bannerProtocol{}, false
+}
+
+// sanitizeBanner escapes line break and non printable byte, so the banner is safe to be written as a single line
+func sanitizeBanner(banner []byte) string {
+	banner = bytes.TrimSpace(banner)
+
+	var builder strings.Builder
+	for _, b := range banner {
+		switch {
+		case b == '\r':
+			builder.WriteString(`\r`)
+		case b == '\n':
+			builder.WriteString(`\n`)
+		case b == '\t':
+			builder.WriteString(`\t`)
+		case b < 0x20 || b > 0x7e:
+			builder.WriteString(fmt.Sprintf(`\x%02x`, b))
+		default:
+			builder.WriteByte(b)
+		}
+	}
+
+	return builder.String()
+}
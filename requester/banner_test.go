@@ -0,0 +1,31 @@
+package requester
+
+import (
+	"testing"
+)
+
+func TestSanitizeBanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		banner []byte
+		want   string
+	}{
+		{"empty", nil, ""},
+		{"printable", []byte("SSH-2.0-OpenSSH_9.6"), "SSH-2.0-OpenSSH_9.6"},
+		{"trimmed", []byte("  220 ready\r\n"), "220 ready"},
+		{"line breaks", []byte("220-first\r\n220 second\r\n"), `220-first\r\n220 second`},
+		{"tab", []byte("a\tb"), `a\tb`},
+		{"control", []byte("a\x00b\x1bc"), `a\x00b\x1bc`},
+		{"delete and high byte", []byte("a\x7fb\xffc"), `a\x7fb\xffc`},
+		{"utf-8", []byte("é"), `\xc3\xa9`},
+		{"backslash kept", []byte(`a\b`), `a\b`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sanitizeBanner(test.banner); got != test.want {
+				t.Errorf("sanitizeBanner(%q) = %q, want %q", test.banner, got, test.want)
+			}
+		})
+	}
+}
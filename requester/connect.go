@@ -0,0 +1,80 @@
+package requester
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Connect checks if port is open by completing tcp handshake (connect scan),
+// unlike nmap syn scan it does not need nmap nor root privileges, the caller waits for RateLimiter
+func (r *Requester) Connect(ctx context.Context, ip string, port int) *ScanResult {
+	result := NewScanResult(ip, port, PROTOCOL_TCP)
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	dialer := net.Dialer{Timeout: r.TimeOut}
+
+	startTime := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	rtt := time.Since(startTime)
+	defer func() {
+		if result.IsAnswered() {
+			result.RTT = rtt
+		}
+	}()
+
+	if err == nil {
+		_ = conn.Close()
+		result.State = PORT_STATE_OPEN
+		result.Reason = "syn-ack"
+		return result
+	}
+
+	result.State, result.Reason = dialErrorToPortState(err)
+	if result.State == PORT_STATE_ERROR {
+		result.SetError(err)
+	}
+
+	return result
+}
+
+// dialErrorToPortState converts dial error to PortState and the reason
+// . refused (RST received) means nothing listen on the port, so it's closed
+// . timeout and unreachable mean the packet is dropped somewhere, so it's filtered
+func dialErrorToPortState(err error) (PortState, string) {
+	if errors.Is(err, context.Canceled) {
+		return PORT_STATE_UNKNOWN, "canceled"
+	}
+
+	if isConnectionRefused(err) {
+		return PORT_STATE_CLOSED, "conn-refused"
+	}
+
+	if errors.Is(err, syscall.EHOSTUNREACH) {
+		return PORT_STATE_FILTERED, "host-unreach"
+	}
+
+	if errors.Is(err, syscall.ENETUNREACH) {
+		return PORT_STATE_FILTERED, "net-unreach"
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return PORT_STATE_FILTERED, "no-response"
+	}
+
+	return PORT_STATE_ERROR, "error"
+}
+
+func isConnectionRefused(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	// windows reports WSAECONNREFUSED which is not syscall.ECONNREFUSED
+	return strings.Contains(strings.ToLower(err.Error()), "refused")
+}
@@ -0,0 +1,154 @@
+package requester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// loopbackPort returns port of a listener on loopback, the listener is closed at the end of the test
+func loopbackPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// closedPort returns port on loopback which nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+
+	return port
+}
+
+func TestConnect(t *testing.T) {
+	r := NewRequester(WithTimeOut(time.Second))
+
+	tests := []struct {
+		name   string
+		port   int
+		state  PortState
+		reason string
+	}{
+		{"open", loopbackPort(t), PORT_STATE_OPEN, "syn-ack"},
+		{"closed", closedPort(t), PORT_STATE_CLOSED, "conn-refused"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := r.Connect(context.Background(), "127.0.0.1", test.port)
+
+			if result.State != test.state || result.Reason != test.reason {
+				t.Errorf("Connect(%d) = %s %s, want %s %s", test.port, result.State, result.Reason, test.state, test.reason)
+			}
+			if result.Protocol != PROTOCOL_TCP || result.Port != test.port {
+				t.Errorf("Connect(%d) = %s/%d, want tcp/%d", test.port, result.Protocol, result.Port, test.port)
+			}
+			if result.RTT <= 0 {
+				t.Errorf("Connect(%d) rtt = %v, want measured rtt of answered port", test.port, result.RTT)
+			}
+		})
+	}
+}
+
+func TestConnectScanner(t *testing.T) {
+	scanner, err := NewScanner(SCANNER_CONNECT, WithTimeOut(time.Second))
+	if err != nil {
+		t.Fatalf("NewScanner(%s) error: %v", SCANNER_CONNECT, err)
+	}
+
+	openPort, closed := loopbackPort(t), closedPort(t)
+	targets := []Target{{IP: "127.0.0.1", Hostname: "localhost"}}
+
+	results, err := scanner.Scan(context.Background(), targets, []int{openPort, closed})
+	if err != nil {
+		t.Fatalf("Scan error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Scan returns %d results, want 2", len(results))
+	}
+
+	if !results[0].IsOpen() || results[1].State != PORT_STATE_CLOSED {
+		t.Errorf("Scan states = %s %s, want open closed", results[0].State, results[1].State)
+	}
+	for _, result := range results {
+		if result.Hostname != "localhost" {
+			t.Errorf("result of port %d hostname = %q, want localhost", result.Port, result.Hostname)
+		}
+	}
+}
+
+func TestDialErrorToPortState(t *testing.T) {
+	opError := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		state  PortState
+		reason string
+	}{
+		{"refused", opError(syscall.ECONNREFUSED), PORT_STATE_CLOSED, "conn-refused"},
+		{"reset", opError(syscall.ECONNRESET), PORT_STATE_CLOSED, "conn-refused"},
+		{"windows refused", errors.New("connectex: No connection could be made because the target machine actively refused it."), PORT_STATE_CLOSED, "conn-refused"},
+		{"host unreachable", opError(syscall.EHOSTUNREACH), PORT_STATE_FILTERED, "host-unreach"},
+		{"net unreachable", opError(syscall.ENETUNREACH), PORT_STATE_FILTERED, "net-unreach"},
+		{"deadline", fmt.Errorf("dial: %w", context.DeadlineExceeded), PORT_STATE_FILTERED, "no-response"},
+		{"timeout", &net.DNSError{IsTimeout: true}, PORT_STATE_FILTERED, "no-response"},
+		{"canceled", fmt.Errorf("dial: %w", context.Canceled), PORT_STATE_UNKNOWN, "canceled"},
+		{"other", errors.New("too many open files"), PORT_STATE_ERROR, "error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state, reason := dialErrorToPortState(test.err)
+			if state != test.state || reason != test.reason {
+				t.Errorf("dialErrorToPortState(%v) = %s %s, want %s %s", test.err, state, reason, test.state, test.reason)
+			}
+		})
+	}
+}
+
+func TestConnectFiltered(t *testing.T) {
+	// nothing answers before the deadline, the port is filtered
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	port := loopbackPort(t)
+	result := NewRequester(WithTimeOut(time.Second)).Connect(ctx, "127.0.0.1", port)
+
+	if result.State != PORT_STATE_FILTERED || result.Reason != "no-response" {
+		t.Errorf("Connect(%d) with expired deadline = %s %s, want filtered no-response", port, result.State, result.Reason)
+	}
+	if result.RTT != 0 {
+		t.Errorf("Connect(%d) rtt = %v, want 0 for unanswered port", port, result.RTT)
+	}
+}
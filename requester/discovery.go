@@ -0,0 +1,287 @@
+package requester
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Ullaakut/nmap/v3"
+)
+
+// ENUM discovery method
+// do not use iota, to make it more readable
+const (
+	DISCOVERY_TCP  = "tcp"  // tcp connect to common ports, refused connection means up too
+	DISCOVERY_ICMP = "icmp" // icmp echo, need raw socket privileges
+	DISCOVERY_NMAP = "nmap" // nmap -sn, which uses arp on local subnet when privileged
+)
+
+// DiscoveryTcpPorts is the default ports of DISCOVERY_TCP
+var DiscoveryTcpPorts = []int{80, 443, 22, 445, 3389}
+
+// reason of a target which can not be checked, it's treated as up so its scan reports the error
+const DISCOVERY_REASON_ERROR = "discovery-error"
+
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// DiscoverHosts checks which targets are up with the methods, a target is up if any of the methods says so,
+// the result maps ip address of every up target to the reason (e.g. tcp-80, echo-reply, arp-response)
+// . tcpPorts is used by DISCOVERY_TCP
+// . a target which a method fails to check is up with DISCOVERY_REASON_ERROR, every failure is in the returned error
+func (r *Requester) DiscoverHosts(ctx context.Context, targets []Target, methods []string, tcpPorts []int) (map[string]string, error) {
+	up := make(map[string]string)
+	var errs []error
+
+	for _, method := range methods {
+		var pending []Target
+		for _, target := range targets {
+			if _, ok := up[target.IP]; !ok {
+				pending = append(pending, target)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		switch method {
+		case DISCOVERY_NMAP:
+			nmapUp, err := r.nmapPingScan(ctx, pending)
+			if err != nil {
+				errs = append(errs, err)
+				for _, target := range pending {
+					up[target.IP] = DISCOVERY_REASON_ERROR
+				}
+				continue
+			}
+			for ip, reason := range nmapUp {
+				up[ip] = reason
+			}
+		case DISCOVERY_TCP, DISCOVERY_ICMP:
+			// every host is probed at the same time, a batch of targets should be small
+			var mutex sync.Mutex
+			var wg sync.WaitGroup
+			for _, target := range pending {
+				wg.Add(1)
+				go func(ip string) {
+					defer wg.Done()
+
+					var isUp bool
+					var reason string
+					var err error
+					if method == DISCOVERY_TCP {
+						isUp, reason, err = r.TcpPing(ctx, ip, tcpPorts)
+					} else {
+						isUp, reason, err = r.IcmpEcho(ctx, ip)
+					}
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					if err != nil {
+						errs = append(errs, fmt.Errorf("%s discovery of %s: %v", method, ip, err))
+						isUp, reason = true, DISCOVERY_REASON_ERROR
+					}
+					if isUp {
+						up[ip] = reason
+					}
+				}(target.IP)
+			}
+			wg.Wait()
+		default:
+			return up, fmt.Errorf("unknown discovery method %q", method)
+		}
+	}
+
+	return up, errors.Join(errs...)
+}
+
+// TcpPing connects to every port at the same time, the host is up if any of them is open or closed,
+// reason is the port which answers first (e.g. tcp-80), error is returned if every port fails (e.g. too many open files)
+func (r *Requester) TcpPing(ctx context.Context, ip string, ports []int) (bool, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	answers := make(chan *ScanResult, len(ports))
+	for _, port := range ports {
+		go func(port int) {
+			if err := r.waitRate(ctx, ip); err != nil {
+				answers <- rateLimitedResult(NewScanResult(ip, port, PROTOCOL_TCP), err)
+				return
+			}
+			answers <- r.Connect(ctx, ip, port)
+		}(port)
+	}
+
+	var failures []error
+	for range ports {
+		result := <-answers
+		if result.IsAnswered() {
+			return true, fmt.Sprintf("tcp-%d", result.Port), nil
+		}
+		if result.State == PORT_STATE_ERROR {
+			failures = append(failures, result.Err)
+		}
+	}
+
+	if len(failures) > 0 && len(failures) == len(ports) {
+		return false, "", failures[0]
+	}
+	return false, "", nil
+}
+
+// IsIcmpPermitted checks if raw icmp socket can be opened, it usually needs root or CAP_NET_RAW
+func IsIcmpPermitted() bool {
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+
+	return true
+}
+
+// IcmpEcho sends icmp echo request and waits for the reply until TimeOut, need raw socket privileges,
+// error is returned if the request can not be sent
+func (r *Requester) IcmpEcho(ctx context.Context, ip string) (bool, string, error) {
+	network, address, requestType, replyType := "ip4:icmp", "0.0.0.0", byte(icmpEchoRequest), byte(icmpEchoReply)
+	if isIPv6(ip) {
+		network, address, requestType, replyType = "ip6:ipv6-icmp", "::", icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	if err := r.waitRate(ctx, ip); err != nil {
+		return false, "", nil
+	}
+
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		return false, "", err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(r.TimeOut)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	// the socket receives every icmp packet of the machine, the id tells which one is ours
+	id := uint16(rand.Intn(1 << 16))
+	message := []byte{requestType, 0, 0, 0, 0, 0, 0, 1, 'i', 'd', 'i', 'e'}
+	binary.BigEndian.PutUint16(message[4:], id)
+	// kernel computes the checksum of icmpv6, but not icmp
+	if requestType == icmpEchoRequest {
+		binary.BigEndian.PutUint16(message[2:], icmpChecksum(message))
+	}
+
+	target := &net.IPAddr{IP: net.ParseIP(ip)}
+	if _, err = conn.WriteTo(message, target); err != nil {
+		// no route to the host means it's down, not a failure
+		if state, _ := dialErrorToPortState(err); state == PORT_STATE_FILTERED {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+
+	buffer := make([]byte, 1500)
+	for ctx.Err() == nil {
+		n, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return false, "", nil
+			}
+			return false, "", err
+		}
+
+		fromIP, ok := from.(*net.IPAddr)
+		if !ok || !fromIP.IP.Equal(target.IP) || n < 8 {
+			continue
+		}
+		if buffer[0] == replyType && binary.BigEndian.Uint16(buffer[4:]) == id {
+			return true, "echo-reply", nil
+		}
+	}
+
+	return false, "", nil
+}
+
+// internet checksum of rfc 1071
+func icmpChecksum(message []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(message); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(message[i:]))
+	}
+	if len(message)%2 == 1 {
+		sum += uint32(message[len(message)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return ^uint16(sum)
+}
+
+// nmapPingScan runs nmap -sn once per ip family, the result maps ip address of up host to the reason
+func (r *Requester) nmapPingScan(ctx context.Context, targets []Target) (map[string]string, error) {
+	var ipv4s, ipv6s []string
+	for _, target := range targets {
+		if isIPv6(target.IP) {
+			ipv6s = append(ipv6s, target.IP)
+		} else {
+			ipv4s = append(ipv4s, target.IP)
+		}
+	}
+
+	up := make(map[string]string)
+	for _, ips := range [][]string{ipv4s, ipv6s} {
+		if len(ips) == 0 {
+			continue
+		}
+
+		options := []nmap.Option{
+			nmap.WithPingScan(),
+			nmap.WithTargets(ips...),
+		}
+		if r.TimeOut > 0 {
+			options = append(options, nmap.WithMaxRTTTimeout(r.TimeOut))
+		}
+		if r.MaxRate > 0 {
+			options = append(options, nmap.WithMaxRate(nmapMaxRate(r.MaxRate)))
+		}
+		if isIPv6(ips[0]) {
+			options = append(options, nmap.WithIPv6Scanning())
+		}
+
+		scanner, err := nmap.NewScanner(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create nmap scanner: %v", err)
+		}
+
+		result, _, err := scanner.Run()
+		if err != nil {
+			return nil, fmt.Errorf("unable to run nmap ping scan: %v", err)
+		}
+
+		for _, host := range result.Hosts {
+			if host.Status.State != "up" {
+				continue
+			}
+			for _, address := range host.Addresses {
+				if ip := net.ParseIP(address.Addr); ip != nil {
+					up[ip.String()] = host.Status.Reason
+				}
+			}
+		}
+	}
+
+	return up, nil
+}
@@ -0,0 +1,118 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// serviceFingerprint matches banner or http Server header,
+// the first submatch is the version and the second one is the extra info
+type serviceFingerprint struct {
+	service string
+	product string
+	pattern *regexp.Regexp
+	cpe     string // :%s suffix is replaced by the version, or removed if there is no version
+}
+
+// the first matched fingerprint is used, put the specific one before the generic one
+var serviceFingerprints = []serviceFingerprint{
+	// ssh
+	{"ssh", "OpenSSH", regexp.MustCompile(`^SSH-[\d.]+-OpenSSH_([\w.]+)(?:\s+(\S+))?`), "cpe:/a:openbsd:openssh:%s"},
+	{"ssh", "Dropbear sshd", regexp.MustCompile(`^SSH-[\d.]+-dropbear_([\w.]+)`), "cpe:/a:matt_johnston:dropbear_ssh_server:%s"},
+	{"ssh", "", regexp.MustCompile(`^SSH-[\d.]+-`), ""},
+
+	// ftp
+	{"ftp", "vsftpd", regexp.MustCompile(`^220 \(vsFTPd ([\w.]+)\)`), "cpe:/a:beasts:vsftpd:%s"},
+	{"ftp", "ProFTPD", regexp.MustCompile(`^220 ProFTPD ([\w.]+)`), "cpe:/a:proftpd:proftpd:%s"},
+	{"ftp", "Pure-FTPd", regexp.MustCompile(`^220.*Pure-FTPd`), "cpe:/a:pureftpd:pure-ftpd"},
+	{"ftp", "", regexp.MustCompile(`^220[ -].*FTP`), ""},
+
+	// smtp
+	{"smtp", "Postfix smtpd", regexp.MustCompile(`^220 \S+ ESMTP Postfix`), "cpe:/a:postfix:postfix"},
+	{"smtp", "Exim smtpd", regexp.MustCompile(`^220 \S+ ESMTP Exim ([\w.]+)`), "cpe:/a:exim:exim:%s"},
+	{"smtp", "", regexp.MustCompile(`^220[ -].*SMTP`), ""},
+
+	// redis
+	{"redis", "Redis key-value store", regexp.MustCompile(`redis_version:([\d.]+)`), "cpe:/a:redislabs:redis:%s"},
+	{"redis", "Redis key-value store", regexp.MustCompile(`^-NOAUTH|^\+PONG`), "cpe:/a:redislabs:redis"},
+
+	// http, matched against Server header too
+	{"http", "nginx", regexp.MustCompile(`nginx/([\d.]+)`), "cpe:/a:igor_sysoev:nginx:%s"},
+	{"http", "Apache httpd", regexp.MustCompile(`Apache/([\d.]+)(?: \(([^)]+)\))?`), "cpe:/a:apache:http_server:%s"},
+	{"http", "Microsoft IIS httpd", regexp.MustCompile(`Microsoft-IIS/([\d.]+)`), "cpe:/a:microsoft:internet_information_services:%s"},
+	{"http", "lighttpd", regexp.MustCompile(`lighttpd/([\d.]+)`), "cpe:/a:lighttpd:lighttpd:%s"},
+	{"http", "Caddy httpd", regexp.MustCompile(`^Caddy$|Server: Caddy`), "cpe:/a:caddyserver:caddy"},
+	{"http", "SimpleHTTPServer", regexp.MustCompile(`SimpleHTTP/([\d.]+) (Python/[\d.]+)`), "cpe:/a:python:python"},
+	{"http", "", regexp.MustCompile(`^HTTP/[\d.]+ \d{3}`), ""},
+}
+
+type serviceProbe struct {
+	requester *Requester
+}
+
+func (p *serviceProbe) Probe(ctx context.Context, result *ScanResult) {
+	// already detected by nmap, never overwrite its service name
+	if result.Protocol != PROTOCOL_TCP || result.Service != "" || result.Product != "" {
+		return
+	}
+
+	// reuse what the other probes got, grab the banner only if there is nothing
+	var texts []string
+	if result.Http != nil && result.Http.Server != "" {
+		texts = append(texts, result.Http.Server)
+	}
+	if result.Banner != "" {
+		texts = append(texts, result.Banner)
+	}
+	if len(texts) == 0 {
+		banner, err := p.requester.GrabBanner(ctx, result.IP, result.Hostname, result.Port)
+		if err != nil {
+			return
+		}
+		texts = append(texts, banner)
+	}
+
+	for _, text := range texts {
+		if matchServiceFingerprint(text, result) {
+			return
+		}
+	}
+
+	// the port speaks http, even though the server is unknown
+	if result.Http != nil && result.Service == "" {
+		result.Service = "http"
+	}
+}
+
+// matchServiceFingerprint fills service, product, version, extra info and cpe of result from the first matched fingerprint
+func matchServiceFingerprint(text string, result *ScanResult) bool {
+	for _, fingerprint := range serviceFingerprints {
+		match := fingerprint.pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		result.Service = fingerprint.service
+		result.Product = fingerprint.product
+		if len(match) > 1 {
+			result.Version = match[1]
+		}
+		if len(match) > 2 {
+			result.ExtraInfo = match[2]
+		}
+
+		if fingerprint.cpe != "" {
+			cpe := strings.TrimSuffix(fingerprint.cpe, ":%s")
+			if result.Version != "" && strings.HasSuffix(fingerprint.cpe, ":%s") {
+				cpe = fmt.Sprintf(fingerprint.cpe, result.Version)
+			}
+			result.CPE = []string{cpe}
+		}
+
+		return true
+	}
+
+	return false
+}
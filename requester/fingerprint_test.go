@@ -0,0 +1,64 @@
+package requester
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchServiceFingerprint(t *testing.T) {
+	tests := []struct {
+		text    string
+		matched bool
+		service string
+		product string
+		version string
+		extra   string
+		cpe     []string
+	}{
+		{"SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13", true, "ssh", "OpenSSH", "9.6p1", "Ubuntu-3ubuntu13", []string{"cpe:/a:openbsd:openssh:9.6p1"}},
+		{"SSH-2.0-dropbear_2022.83", true, "ssh", "Dropbear sshd", "2022.83", "", []string{"cpe:/a:matt_johnston:dropbear_ssh_server:2022.83"}},
+		{"SSH-2.0-Go", true, "ssh", "", "", "", nil},
+		{"220 (vsFTPd 3.0.5)", true, "ftp", "vsftpd", "3.0.5", "", []string{"cpe:/a:beasts:vsftpd:3.0.5"}},
+		{"220---------- Welcome to Pure-FTPd [privsep] ----------", true, "ftp", "Pure-FTPd", "", "", []string{"cpe:/a:pureftpd:pure-ftpd"}},
+		{"220 mail.example.com ESMTP Postfix (Ubuntu)", true, "smtp", "Postfix smtpd", "", "", []string{"cpe:/a:postfix:postfix"}},
+		{"-NOAUTH Authentication required.", true, "redis", "Redis key-value store", "", "", []string{"cpe:/a:redislabs:redis"}},
+		{"nginx/1.24.0", true, "http", "nginx", "1.24.0", "", []string{"cpe:/a:igor_sysoev:nginx:1.24.0"}},
+		{"Apache/2.4.58 (Ubuntu)", true, "http", "Apache httpd", "2.4.58", "Ubuntu", []string{"cpe:/a:apache:http_server:2.4.58"}},
+		{"Apache", false, "", "", "", "", nil},
+		{"HTTP/1.1 400 Bad Request", true, "http", "", "", "", nil},
+		{"hello", false, "", "", "", "", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			result := NewScanResult("10.0.0.1", 80, PROTOCOL_TCP)
+
+			if matched := matchServiceFingerprint(test.text, result); matched != test.matched {
+				t.Fatalf("matchServiceFingerprint(%q) = %t, want %t", test.text, matched, test.matched)
+			}
+
+			got := []string{result.Service, result.Product, result.Version, result.ExtraInfo}
+			want := []string{test.service, test.product, test.version, test.extra}
+			if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(result.CPE, test.cpe) {
+				t.Errorf("matchServiceFingerprint(%q) = %q %v, want %q %v", test.text, got, result.CPE, want, test.cpe)
+			}
+		})
+	}
+}
+
+func TestServiceProbeKeepsNmapService(t *testing.T) {
+	// nmap names the port without product, the banner must not rename it
+	result := NewScanResult("10.0.0.1", 8080, PROTOCOL_TCP)
+	result.State = PORT_STATE_OPEN
+	result.Service = "http-proxy"
+	result.Banner = "HTTP/1.1 200 OK"
+
+	probe := &serviceProbe{requester: NewRequester(WithTimeOut(time.Second))}
+	probe.Probe(context.Background(), result)
+
+	if result.Service != "http-proxy" || result.Product != "" {
+		t.Errorf("service after probe = %q %q, want http-proxy without product", result.Service, result.Product)
+	}
+}
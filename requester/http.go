@@ -0,0 +1,144 @@
+package requester
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// body longer than this is not read, the content hash is of the read part only
+	httpMaxBodySize = 1 << 20
+	// title longer than this is truncated
+	httpMaxTitleSize = 256
+)
+
+var httpTitleRegexp = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// 400 body of https port which receives plain http request (go, nginx, apache)
+var httpsPortRegexp = regexp.MustCompile(`(?i)http request to an https server|plain http request|plain http to an ssl`)
+
+// HttpInfo is the response of GET / of a http(s) port
+type HttpInfo struct {
+	Url         string
+	StatusCode  int
+	Server      string // Server header
+	Title       string // <title> of the page
+	Location    string // Location header, redirect is not followed
+	ContentHash string // sha256 hex of the body
+}
+
+type httpProbe struct {
+	requester *Requester
+}
+
+func (p *httpProbe) Probe(ctx context.Context, result *ScanResult) {
+	if result.Protocol != PROTOCOL_TCP {
+		return
+	}
+
+	// the port speaks tls if PROBE_TLS got its certificate, otherwise try http first
+	schemes := []string{"http", "https"}
+	if result.Tls != nil {
+		schemes = []string{"https"}
+	}
+
+	for _, scheme := range schemes {
+		info, err := p.requester.HttpFingerprint(ctx, scheme, result.IP, result.Hostname, result.Port)
+		if err == nil {
+			result.Http = info
+			return
+		}
+	}
+}
+
+// HttpFingerprint requests GET / of the port with scheme (http, https),
+// hostname is used as Host header and SNI if it's not empty, the request is sent to ip anyway
+func (r *Requester) HttpFingerprint(ctx context.Context, scheme string, ip string, hostname string, port int) (*HttpInfo, error) {
+	if err := r.waitRate(ctx, ip); err != nil {
+		return nil, err
+	}
+
+	host := hostname
+	if host == "" {
+		host = ip
+	}
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	url := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/"
+
+	dialer := net.Dialer{Timeout: r.TimeOut}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			TLSClientConfig: &tls.Config{
+				ServerName:         hostname,
+				InsecureSkipVerify: true, // fingerprint only, the certificate is not trusted
+			},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.TimeOut)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", "idie")
+
+	// tls record as the reply of plain http request fails here as malformed response
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, httpMaxBodySize))
+	if err != nil && len(body) == 0 {
+		return nil, fmt.Errorf("unable to read body: %v", err)
+	}
+
+	if scheme == "http" && response.StatusCode == http.StatusBadRequest && httpsPortRegexp.Match(body) {
+		return nil, fmt.Errorf("%s is https port", address)
+	}
+	hash := sha256.Sum256(body)
+
+	return &HttpInfo{
+		Url:         url,
+		StatusCode:  response.StatusCode,
+		Server:      response.Header.Get("Server"),
+		Title:       htmlTitle(body),
+		Location:    response.Header.Get("Location"),
+		ContentHash: hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// htmlTitle returns the unescaped <title> of the body in a single line, empty if there is none
+func htmlTitle(body []byte) string {
+	match := httpTitleRegexp.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+
+	title := strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+	if len(title) > httpMaxTitleSize {
+		title = title[:httpMaxTitleSize]
+	}
+
+	return title
+}
@@ -0,0 +1,276 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ullaakut/nmap/v3"
+)
+
+const (
+	// up to 1000 ports of a target in a single nmap process, progress is reported once a process finishes,
+	// so a large port list of a single target still moves the progress bar
+	nmapBatchTargets = 1
+	nmapBatchPorts   = 1000
+
+	nmapInitialRTTTimeOut = 1 * time.Second
+	// extra time for nmap to start and finish, on top of host timeout
+	nmapTimeOutSlack = 1 * time.Minute
+
+	// reason of port which nmap groups into extraports along with other states, see nmapRescanNotReported
+	nmapReasonNotReported = "not-reported"
+)
+
+type nmapScanner struct {
+	requester *Requester
+	scanType  nmap.Option
+	protocol  string
+}
+
+func (s *nmapScanner) Protocol() string {
+	return s.protocol
+}
+
+func (s *nmapScanner) BatchSize() (targets int, ports int) {
+	return nmapBatchTargets, nmapBatchPorts
+}
+
+// IsRetryable retries only nmap failure and filtered port without response,
+// open|filtered is the usual result of udp port which nmap already retransmits to
+func (s *nmapScanner) IsRetryable(result *ScanResult) bool {
+	return result.State == PORT_STATE_ERROR || (result.State == PORT_STATE_FILTERED && result.Reason == "no-response")
+}
+
+func (s *nmapScanner) Scan(ctx context.Context, targets []Target, ports []int) ([]*ScanResult, error) {
+	return s.requester.nmapScan(ctx, s.scanType, s.protocol, targets, ports)
+}
+
+// nmapScan runs nmap with scanType option (e.g. nmap.WithSYNScan()) against every target and port,
+// nmap is run once per ip family since ipv6 needs -6 option, a result is returned for each (target, port)
+// . protocol is the protocol of the scanned ports
+func (r *Requester) nmapScan(ctx context.Context, scanType nmap.Option, protocol string, targets []Target, ports []int) ([]*ScanResult, error) {
+	var ipv4Targets, ipv6Targets []Target
+	for _, target := range targets {
+		if isIPv6(target.IP) {
+			ipv6Targets = append(ipv6Targets, target)
+		} else {
+			ipv4Targets = append(ipv4Targets, target)
+		}
+	}
+
+	var results []*ScanResult
+	for _, familyTargets := range [][]Target{ipv4Targets, ipv6Targets} {
+		if len(familyTargets) == 0 {
+			continue
+		}
+
+		familyResults, err := r.nmapRun(ctx, scanType, protocol, familyTargets, ports)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, familyResults...)
+	}
+
+	return results, nil
+}
+
+func (r *Requester) nmapRun(ctx context.Context, scanType nmap.Option, protocol string, targets []Target, ports []int) ([]*ScanResult, error) {
+	// nmap stops probing a host after HostTimeOut by itself, this is for nmap which hangs
+	if r.HostTimeOut > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.HostTimeOut*time.Duration(len(targets))+nmapTimeOutSlack)
+		defer cancel()
+	}
+
+	var ips []string
+	for _, target := range targets {
+		ips = append(ips, target.IP)
+	}
+
+	var portList []string
+	for _, port := range ports {
+		portList = append(portList, strconv.Itoa(port))
+	}
+
+	// Equivalent to `/usr/local/bin/nmap -p 80,443,843 -sS -Pn --max-rtt-timeout 3000ms --host-timeout 300000ms 10.0.0.1 10.0.0.2`
+	// targets are either found up by DiscoverHosts or the discovery is skipped on purpose,
+	// so nmap must not drop a target by its own host discovery (-Pn)
+	options := []nmap.Option{
+		scanType,
+		nmap.WithTargets(ips...),
+		nmap.WithPorts(strings.Join(portList, ",")),
+		nmap.WithSkipHostDiscovery(),
+	}
+	if r.TimeOut > 0 {
+		// initial rtt timeout must not be greater than max rtt timeout (default initial is 1s)
+		initialTimeOut := r.TimeOut
+		if initialTimeOut > nmapInitialRTTTimeOut {
+			initialTimeOut = nmapInitialRTTTimeOut
+		}
+		options = append(options, nmap.WithInitialRTTTimeout(initialTimeOut), nmap.WithMaxRTTTimeout(r.TimeOut))
+	}
+	if r.HostTimeOut > 0 {
+		options = append(options, nmap.WithHostTimeout(r.HostTimeOut))
+	}
+	if isIPv6(ips[0]) {
+		options = append(options, nmap.WithIPv6Scanning())
+	}
+	if r.ServiceDetect {
+		options = append(options, nmap.WithServiceInfo())
+	}
+	if r.MaxRate > 0 {
+		options = append(options, nmap.WithMaxRate(nmapMaxRate(r.MaxRate)))
+	}
+
+	scanner, err := nmap.NewScanner(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create nmap scanner: %v", err)
+	}
+
+	startTime := time.Now()
+	result, warnings, err := scanner.Run()
+	duration := time.Since(startTime)
+	if err != nil {
+		// warnings of nmap usually tell why it failed
+		if warnings != nil && len(*warnings) > 0 {
+			return nil, fmt.Errorf("unable to run nmap scan: %v: %s", err, strings.Join(*warnings, "; "))
+		}
+		return nil, fmt.Errorf("unable to run nmap scan: %v", err)
+	}
+
+	hosts := make(map[string]*nmap.Host)
+	for i := range result.Hosts {
+		for _, address := range result.Hosts[i].Addresses {
+			if ip := net.ParseIP(address.Addr); ip != nil {
+				hosts[ip.String()] = &result.Hosts[i]
+			}
+		}
+	}
+
+	var results []*ScanResult
+	for _, target := range targets {
+		host := hosts[net.ParseIP(target.IP).String()]
+
+		for _, port := range ports {
+			scanResult := NewScanResult(target.IP, port, protocol)
+			scanResult.Hostname = target.Hostname
+			scanResult.StartedAt = startTime
+			scanResult.Duration = duration
+			nmapHostToScanResult(host, scanResult)
+			results = append(results, scanResult)
+		}
+	}
+
+	r.nmapRescanNotReported(ctx, scanType, protocol, targets, results)
+
+	return results, nil
+}
+
+// nmapRescanNotReported scans again the ports which nmap groups into more than one extraports,
+// so their state is unknown, the ports are scanned in halves until nmap reports each of them (a single port always is)
+func (r *Requester) nmapRescanNotReported(ctx context.Context, scanType nmap.Option, protocol string, targets []Target, results []*ScanResult) {
+	for _, target := range targets {
+		indexes := make(map[int]int) // port to index of its result
+		var ports []int
+		for i, result := range results {
+			if result.IP == target.IP && result.Reason == nmapReasonNotReported {
+				indexes[result.Port] = i
+				ports = append(ports, result.Port)
+			}
+		}
+		if len(ports) < 2 {
+			continue
+		}
+
+		for _, half := range [][]int{ports[:len(ports)/2], ports[len(ports)/2:]} {
+			rescanResults, err := r.nmapRun(ctx, scanType, protocol, []Target{target}, half)
+			if err != nil {
+				for _, port := range half {
+					results[indexes[port]].SetError(err)
+				}
+				continue
+			}
+
+			for _, rescanResult := range rescanResults {
+				results[indexes[rescanResult.Port]] = rescanResult
+			}
+		}
+	}
+}
+
+// nmapHostToScanResult fills scanResult from the nmap result of its host
+func nmapHostToScanResult(host *nmap.Host, scanResult *ScanResult) {
+	// nmap does not report host which is down
+	if host == nil {
+		scanResult.Reason = "host-down"
+		return
+	}
+
+	// nmap measures the latency of the host, not of every port
+	defer func() {
+		if scanResult.IsAnswered() {
+			scanResult.RTT = nmapTimeToDuration(host.Times.SRTT)
+		}
+	}()
+
+	for _, port := range host.Ports {
+		if int(port.ID) != scanResult.Port || port.Protocol != scanResult.Protocol {
+			continue
+		}
+
+		scanResult.State = nmapStateToPortState(port.State.State)
+		scanResult.Service = port.Service.Name
+		scanResult.Product = port.Service.Product
+		scanResult.Version = port.Service.Version
+		scanResult.ExtraInfo = port.Service.ExtraInfo
+		for _, cpe := range port.Service.CPEs {
+			scanResult.CPE = append(scanResult.CPE, string(cpe))
+		}
+		scanResult.Reason = port.State.Reason
+		return
+	}
+
+	// nmap gives up the host after --host-timeout
+	if host.TimedOut {
+		scanResult.Reason = "host-timeout"
+		return
+	}
+
+	// ports with the same state are grouped into extraports when there are many of them,
+	// the state is only known if there is a single group
+	if len(host.ExtraPorts) == 1 {
+		extraPort := host.ExtraPorts[0]
+		scanResult.State = nmapStateToPortState(extraPort.State)
+		if len(extraPort.Reasons) == 1 {
+			scanResult.Reason = extraPort.Reasons[0].Reason
+		}
+		return
+	}
+
+	scanResult.Reason = nmapReasonNotReported
+}
+
+// nmap max rate is an integer, at least 1 packet per second
+func nmapMaxRate(packetsPerSecond float64) int {
+	return max(1, int(packetsPerSecond))
+}
+
+// nmap need -6 option to scan ipv6 address
+func isIPv6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
+
+// nmap reports times in microseconds
+func nmapTimeToDuration(s string) time.Duration {
+	microseconds, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(microseconds) * time.Microsecond
+}
@@ -0,0 +1,76 @@
+package requester
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ullaakut/nmap/v3"
+)
+
+// open ssh port reported by nmap -sV
+var nmapSshPort = nmap.Port{
+	ID:       22,
+	Protocol: PROTOCOL_TCP,
+	State:    nmap.State{State: "open", Reason: "syn-ack"},
+	Service:  nmap.Service{Name: "ssh", Product: "OpenSSH", Version: "9.6", ExtraInfo: "protocol 2.0", CPEs: []nmap.CPE{"cpe:/a:openbsd:openssh:9.6"}},
+}
+
+func TestNmapHostToScanResult(t *testing.T) {
+	extraPorts := func(states ...string) []nmap.ExtraPort {
+		var groups []nmap.ExtraPort
+		for _, state := range states {
+			groups = append(groups, nmap.ExtraPort{State: state, Count: 10, Reasons: []nmap.Reason{{Reason: state + "-reason", Count: 10}}})
+		}
+		return groups
+	}
+
+	tests := []struct {
+		name     string
+		host     *nmap.Host
+		port     int
+		protocol string
+		state    PortState
+		reason   string
+		rtt      time.Duration
+	}{
+		{"host down", nil, 22, PROTOCOL_TCP, PORT_STATE_UNKNOWN, "host-down", 0},
+		{"reported port", &nmap.Host{Ports: []nmap.Port{nmapSshPort}, Times: nmap.Times{SRTT: "1500"}}, 22, PROTOCOL_TCP, PORT_STATE_OPEN, "syn-ack", 1500 * time.Microsecond},
+		{"other protocol", &nmap.Host{Ports: []nmap.Port{nmapSshPort}, ExtraPorts: extraPorts("open|filtered")}, 22, PROTOCOL_UDP, PORT_STATE_OPEN_FILTERED, "open|filtered-reason", 0},
+		{"host timeout", &nmap.Host{Ports: []nmap.Port{nmapSshPort}, TimedOut: true, ExtraPorts: extraPorts("filtered")}, 80, PROTOCOL_TCP, PORT_STATE_UNKNOWN, "host-timeout", 0},
+		{"single extraports", &nmap.Host{ExtraPorts: extraPorts("closed"), Times: nmap.Times{SRTT: "800"}}, 80, PROTOCOL_TCP, PORT_STATE_CLOSED, "closed-reason", 800 * time.Microsecond},
+		{"filtered extraports without rtt", &nmap.Host{ExtraPorts: extraPorts("filtered"), Times: nmap.Times{SRTT: "800"}}, 80, PROTOCOL_TCP, PORT_STATE_FILTERED, "filtered-reason", 0},
+		{"mixed extraports", &nmap.Host{ExtraPorts: extraPorts("closed", "filtered")}, 80, PROTOCOL_TCP, PORT_STATE_UNKNOWN, nmapReasonNotReported, 0},
+		{"no extraports", &nmap.Host{}, 80, PROTOCOL_TCP, PORT_STATE_UNKNOWN, nmapReasonNotReported, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := NewScanResult("10.0.0.1", test.port, test.protocol)
+			nmapHostToScanResult(test.host, result)
+
+			if result.State != test.state || result.Reason != test.reason {
+				t.Errorf("state = %s %s, want %s %s", result.State, result.Reason, test.state, test.reason)
+			}
+			if result.RTT != test.rtt {
+				t.Errorf("rtt = %v, want %v", result.RTT, test.rtt)
+			}
+		})
+	}
+}
+
+func TestNmapHostToScanResultService(t *testing.T) {
+	host := &nmap.Host{Ports: []nmap.Port{nmapSshPort}}
+
+	result := NewScanResult("10.0.0.1", 22, PROTOCOL_TCP)
+	nmapHostToScanResult(host, result)
+
+	got := []string{result.Service, result.Product, result.Version, result.ExtraInfo}
+	want := []string{"ssh", "OpenSSH", "9.6", "protocol 2.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("service = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(result.CPE, []string{"cpe:/a:openbsd:openssh:9.6"}) {
+		t.Errorf("cpe = %v, want [cpe:/a:openbsd:openssh:9.6]", result.CPE)
+	}
+}
@@ -0,0 +1,82 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ENUM probe name
+// do not use iota, to make it more readable
+const (
+	PROBE_BANNER  = "banner"  // read banner of open tcp port
+	PROBE_TLS     = "tls"     // collect tls certificate of open tcp port
+	PROBE_HTTP    = "http"    // fingerprint http(s) service of open tcp port, run it after PROBE_TLS
+	PROBE_SERVICE = "service" // detect service and version of open tcp port, run it after the other probes
+)
+
+// Probe collects more information of an open port into its result, register new implementation with RegisterProbe
+type Probe interface {
+	// Probe is run after the port is found open, failure of the probe does not change the port state
+	Probe(ctx context.Context, result *ScanResult)
+}
+
+// ProbeFactory creates Probe which uses the configuration of the Requester
+type ProbeFactory func(r *Requester) Probe
+
+var (
+	probeFactories      = make(map[string]ProbeFactory)
+	probeFactoriesMutex sync.RWMutex
+)
+
+func init() {
+	RegisterProbe(PROBE_BANNER, func(r *Requester) Probe {
+		return &bannerProbe{requester: r}
+	})
+	RegisterProbe(PROBE_TLS, func(r *Requester) Probe {
+		return &tlsProbe{requester: r}
+	})
+	RegisterProbe(PROBE_HTTP, func(r *Requester) Probe {
+		return &httpProbe{requester: r}
+	})
+	RegisterProbe(PROBE_SERVICE, func(r *Requester) Probe {
+		return &serviceProbe{requester: r}
+	})
+}
+
+// RegisterProbe registers probe factory by name, registering the same name replaces the previous one
+func RegisterProbe(name string, factory ProbeFactory) {
+	probeFactoriesMutex.Lock()
+	defer probeFactoriesMutex.Unlock()
+
+	probeFactories[name] = factory
+}
+
+// NewProbe creates registered probe by name
+func NewProbe(name string, fields ...Option) (Probe, error) {
+	probeFactoriesMutex.RLock()
+	factory, ok := probeFactories[name]
+	probeFactoriesMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown probe %q", name)
+	}
+
+	return factory(NewRequester(fields...)), nil
+}
+
+// RunProbes runs every probe against every open port in results, one after another
+func RunProbes(ctx context.Context, results []*ScanResult, probes []Probe) {
+	for _, result := range results {
+		if !result.IsOpen() {
+			continue
+		}
+
+		for _, probe := range probes {
+			if ctx.Err() != nil {
+				return
+			}
+			probe.Probe(ctx, result)
+		}
+	}
+}
@@ -2,29 +2,41 @@ package requester
 
 import (
 	"context"
-	"fmt"
-	"net"
-	"strconv"
+	"errors"
 	"time"
-
-	"github.com/Ullaakut/nmap/v3"
 )
 
 type Requester struct {
 	//public
-	TimeOut time.Duration
+	TimeOut     time.Duration // time to wait for a response of a single probe
+	HostTimeOut time.Duration // time to wait for every probe of a host, 0 means no limit
+	// detect product and version of open port (nmap -sV), pure go scanners use PROBE_SERVICE instead
+	ServiceDetect bool
+	// RateLimiter is waited before every probe, nil means no limit
+	RateLimiter RateLimiter
+	// MaxRate is packets per second of a single nmap process, 0 means no limit
+	MaxRate float64
+}
+
+// RateLimiter limits the rate of probes to ip address, it should be shared by every Requester
+type RateLimiter interface {
+	// Wait blocks until a probe to ip address is allowed, error is returned if ctx is done first
+	Wait(ctx context.Context, ip string) error
 }
 
 type Option func(*Requester)
 
 const (
 	// timeout is the time to wait for a response.
-	pingTimeOut = 5 * time.Minute
+	pingTimeOut = 3 * time.Second
+	// host timeout is the time to wait for every response of a host.
+	hostTimeOut = 5 * time.Minute
 )
 
 func NewRequester(fields ...Option) *Requester {
 	r := &Requester{
-		TimeOut: pingTimeOut,
+		TimeOut:     pingTimeOut,
+		HostTimeOut: hostTimeOut,
 	}
 
 	for _, field := range fields {
@@ -40,79 +52,43 @@ func WithTimeOut(timeOut time.Duration) Option {
 	}
 }
 
-// ping ip address with defined port is open or not
-// best for local network
-func (r *Requester) PingTcp(ip string, port int) bool {
-	address := net.JoinHostPort(ip, strconv.Itoa(port)) //fmt.Sprintf("%s:%d", ip, port)
-
-	conn, err := net.DialTimeout("tcp", address, pingTimeOut)
-	if err != nil {
-		return false
-	}
-	if conn == nil {
-		return false
+func WithHostTimeOut(timeOut time.Duration) Option {
+	return func(r *Requester) {
+		r.HostTimeOut = timeOut
 	}
-
-	defer func(conn net.Conn) {
-		err := conn.Close()
-		if err != nil {
-			//
-		}
-	}(conn)
-
-	return true
 }
 
-// ping syn
-func (r *Requester) NmapSyn(ip string, port int) (ipRet string, portRet int, isOpen bool, protocol string, serviceName string) {
-	if r.TimeOut < 5*time.Minute {
-		r.TimeOut = pingTimeOut
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), r.TimeOut)
-	defer cancel()
-
-	// Equivalent to `/usr/local/bin/nmap -p 80,443,843 -sS ipv4`,
-	// with a 5-minute timeout.
-	scanner, err := nmap.NewScanner(
-		ctx,
-		nmap.WithSYNScan(),
-		nmap.WithTargets(ip),
-		nmap.WithPorts(strconv.Itoa(port)),
-	)
-	if err != nil {
-		panic(fmt.Sprintf("unable to create nmap scanner: %v", err))
+func WithServiceDetect(enabled bool) Option {
+	return func(r *Requester) {
+		r.ServiceDetect = enabled
 	}
+}
 
-	result, warnings, err := scanner.Run()
-	if len(*warnings) > 0 {
-		//
-	}
-	if err != nil {
-		panic(fmt.Sprintf("unable to run nmap scan: %v", err))
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(r *Requester) {
+		r.RateLimiter = limiter
 	}
+}
 
-	ipRet = ip
-	portRet = port
-	isOpen = false
-	protocol = ""
-	serviceName = ""
-
-	if len(result.Hosts) < 1 {
-		return
+func WithMaxRate(packetsPerSecond float64) Option {
+	return func(r *Requester) {
+		r.MaxRate = packetsPerSecond
 	}
+}
 
-	host := result.Hosts[0]
-	if len(host.Ports) == 0 || len(host.Addresses) == 0 {
-		return
+// waitRate waits for RateLimiter before probing ip address
+func (r *Requester) waitRate(ctx context.Context, ip string) error {
+	if r.RateLimiter == nil {
+		return nil
 	}
+	return r.RateLimiter.Wait(ctx, ip)
+}
 
-	scanResult := host.Ports[0]
-	if scanResult.State.String() == "open" {
-		isOpen = true
+// rateLimitedResult is the result of the probe which is not sent since ctx is done while waiting for RateLimiter
+func rateLimitedResult(result *ScanResult, err error) *ScanResult {
+	result.Reason = "canceled"
+	if errors.Is(err, context.DeadlineExceeded) {
+		result.Reason = "host-timeout"
 	}
-	protocol = scanResult.Protocol
-	serviceName = scanResult.Service.Name
-
-	return
+	return result
 }
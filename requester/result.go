@@ -0,0 +1,125 @@
+package requester
+
+import (
+	"time"
+)
+
+type PortState string
+
+// ENUM protocol of ScanResult
+// do not use iota, to make it more readable
+const (
+	PROTOCOL_TCP = "tcp"
+	PROTOCOL_UDP = "udp"
+)
+
+// ENUM PortState
+// do not use iota, to make it more readable
+const (
+	PORT_STATE_OPEN          PortState = "open"
+	PORT_STATE_CLOSED        PortState = "closed"
+	PORT_STATE_FILTERED      PortState = "filtered"
+	PORT_STATE_OPEN_FILTERED PortState = "open|filtered" // no response, common for udp port which ignores the probe
+	PORT_STATE_UNKNOWN       PortState = "unknown"
+	PORT_STATE_ERROR         PortState = "error" // the port can not be scanned, see ScanResult.Err
+)
+
+// ScanResult is the result of scanning a single port of an ip address
+type ScanResult struct {
+	IP        string
+	Hostname  string // hostname target which is resolved to IP, empty for ip target
+	Port      int
+	Protocol  string
+	State     PortState
+	Service   string
+	Product   string   // e.g. OpenSSH, see Requester.ServiceDetect
+	Version   string   // version of the product
+	ExtraInfo string   // e.g. protocol 2.0, Ubuntu
+	CPE       []string // common platform enumeration of the product
+	Reason    string
+	RTT       time.Duration // round trip time of the answer, 0 if there is no answer (e.g. filtered)
+	Err       error
+	Timestamp time.Time
+	StartedAt time.Time     // when the probe started, for nmap it's when the nmap process started
+	Duration  time.Duration // how long the probe took, for nmap it's the whole nmap process
+	Attempts  int           // count of probes until the state is final, 1 if it's not retried
+	Banner    string        // sanitized banner of open port, see PROBE_BANNER
+	Tls       *TlsInfo      // nil if the port does not speak tls, see PROBE_TLS
+	Http      *HttpInfo     // nil if the port does not speak http, see PROBE_HTTP
+}
+
+func NewScanResult(ip string, port int, protocol string) *ScanResult {
+	now := time.Now()
+
+	return &ScanResult{
+		IP:        ip,
+		Port:      port,
+		Protocol:  protocol,
+		State:     PORT_STATE_UNKNOWN,
+		Timestamp: now,
+		StartedAt: now,
+		Attempts:  1,
+	}
+}
+
+func (s *ScanResult) IsOpen() bool {
+	return s.State == PORT_STATE_OPEN
+}
+
+// IsInconclusive returns true if the probe times out or fails, so it's worth to retry
+func (s *ScanResult) IsInconclusive() bool {
+	switch s.State {
+	case PORT_STATE_ERROR, PORT_STATE_OPEN_FILTERED:
+		return true
+	case PORT_STATE_FILTERED:
+		return s.Reason == "no-response"
+	}
+	return false
+}
+
+// IsAnswered returns true if the port answers the probe, so its RTT is measured
+func (s *ScanResult) IsAnswered() bool {
+	return s.State == PORT_STATE_OPEN || s.State == PORT_STATE_CLOSED
+}
+
+// SetError marks the result as failed to scan
+func (s *ScanResult) SetError(err error) {
+	s.State = PORT_STATE_ERROR
+	s.Err = err
+	if s.Reason == "" {
+		s.Reason = "error"
+	}
+}
+
+// NewErrorResults creates failed result for every (target, port)
+func NewErrorResults(targets []Target, ports []int, protocol string, err error) []*ScanResult {
+	var results []*ScanResult
+
+	for _, target := range targets {
+		for _, port := range ports {
+			result := NewScanResult(target.IP, port, protocol)
+			result.Hostname = target.Hostname
+			result.SetError(err)
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// convert nmap port state to PortState
+// closed|filtered is reported as filtered
+func nmapStateToPortState(state string) PortState {
+	switch state {
+	case "open":
+		return PORT_STATE_OPEN
+	case "closed":
+		return PORT_STATE_CLOSED
+	case "open|filtered":
+		return PORT_STATE_OPEN_FILTERED
+	case "filtered", "closed|filtered":
+		return PORT_STATE_FILTERED
+	}
+
+	return PORT_STATE_UNKNOWN
+}
@@ -0,0 +1,180 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ullaakut/nmap/v3"
+)
+
+// ENUM scanner name
+// do not use iota, to make it more readable
+const (
+	SCANNER_SYN          = "syn"          // nmap syn scan, need nmap and root privileges
+	SCANNER_NMAP_CONNECT = "nmap-connect" // nmap tcp connect scan
+	SCANNER_NMAP_UDP     = "nmap-udp"     // nmap udp scan, need nmap and root privileges
+	SCANNER_CONNECT      = "connect"      // tcp connect scan, no dependency
+	SCANNER_UDP          = "udp"          // udp scan, no dependency
+)
+
+// Target is an ip address to scan, hostname is set if the ip address is resolved from hostname
+type Target struct {
+	IP       string
+	Hostname string
+}
+
+// Scanner scans ports of targets, register new implementation with RegisterScanner
+type Scanner interface {
+	// Protocol returns protocol of the scanned ports (tcp, udp)
+	Protocol() string
+	// Scan scans every port of every target and returns a result for each of them,
+	// error is returned only when the scan can not be done at all
+	Scan(ctx context.Context, targets []Target, ports []int) ([]*ScanResult, error)
+}
+
+// BatchScanner is Scanner which is more efficient to scan many targets and ports at once
+type BatchScanner interface {
+	Scanner
+	// BatchSize returns preferred count of targets and ports in a single Scan, less than 1 means all of them
+	BatchSize() (targets int, ports int)
+}
+
+// RetryScanner is Scanner which decides which inconclusive result is worth to retry,
+// e.g. nmap retransmits probes by itself, so most of its open|filtered results are final
+type RetryScanner interface {
+	Scanner
+	// IsRetryable returns true if the result of Scan should be scanned again
+	IsRetryable(result *ScanResult) bool
+}
+
+// ScannerFactory creates Scanner which uses the configuration of the Requester
+type ScannerFactory func(r *Requester) Scanner
+
+var (
+	scannerFactories      = make(map[string]ScannerFactory)
+	scannerFactoriesMutex sync.RWMutex
+)
+
+func init() {
+	RegisterScanner(SCANNER_SYN, func(r *Requester) Scanner {
+		return &nmapScanner{requester: r, scanType: nmap.WithSYNScan(), protocol: PROTOCOL_TCP}
+	})
+	RegisterScanner(SCANNER_NMAP_CONNECT, func(r *Requester) Scanner {
+		return &nmapScanner{requester: r, scanType: nmap.WithConnectScan(), protocol: PROTOCOL_TCP}
+	})
+	RegisterScanner(SCANNER_NMAP_UDP, func(r *Requester) Scanner {
+		return &nmapScanner{requester: r, scanType: nmap.WithUDPScan(), protocol: PROTOCOL_UDP}
+	})
+	RegisterScanner(SCANNER_CONNECT, func(r *Requester) Scanner {
+		return &connectScanner{requester: r}
+	})
+	RegisterScanner(SCANNER_UDP, func(r *Requester) Scanner {
+		return &udpScanner{requester: r}
+	})
+}
+
+// RegisterScanner registers scanner factory by name, registering the same name replaces the previous one
+func RegisterScanner(name string, factory ScannerFactory) {
+	scannerFactoriesMutex.Lock()
+	defer scannerFactoriesMutex.Unlock()
+
+	scannerFactories[name] = factory
+}
+
+// NewScanner creates registered scanner by name
+func NewScanner(name string, fields ...Option) (Scanner, error) {
+	scannerFactoriesMutex.RLock()
+	factory, ok := scannerFactories[name]
+	scannerFactoriesMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown scanner %q", name)
+	}
+
+	return factory(NewRequester(fields...)), nil
+}
+
+// ScannerNames returns sorted names of registered scanners
+func ScannerNames() []string {
+	scannerFactoriesMutex.RLock()
+	defer scannerFactoriesMutex.RUnlock()
+
+	var names []string
+	for name := range scannerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+type connectScanner struct {
+	requester *Requester
+}
+
+func (s *connectScanner) Protocol() string {
+	return PROTOCOL_TCP
+}
+
+func (s *connectScanner) Scan(ctx context.Context, targets []Target, ports []int) ([]*ScanResult, error) {
+	return s.requester.probeTargets(ctx, targets, ports, PROTOCOL_TCP, s.requester.Connect), nil
+}
+
+type udpScanner struct {
+	requester *Requester
+}
+
+func (s *udpScanner) Protocol() string {
+	return PROTOCOL_UDP
+}
+
+func (s *udpScanner) Scan(ctx context.Context, targets []Target, ports []int) ([]*ScanResult, error) {
+	return s.requester.probeTargets(ctx, targets, ports, PROTOCOL_UDP, s.requester.Udp), nil
+}
+
+// probeTargets runs probe against every port of every target,
+// probing a target stops when HostTimeOut is reached and the rest of its ports are reported as host-timeout,
+// waiting for RateLimiter is not counted in HostTimeOut nor in the timing of the result
+func (r *Requester) probeTargets(ctx context.Context, targets []Target, ports []int, protocol string, probe func(ctx context.Context, ip string, port int) *ScanResult) []*ScanResult {
+	var results []*ScanResult
+
+	for _, target := range targets {
+		// probing time left of the target
+		remaining := r.HostTimeOut
+
+		for _, port := range ports {
+			var result *ScanResult
+			if r.HostTimeOut > 0 && remaining <= 0 && ctx.Err() == nil {
+				result = NewScanResult(target.IP, port, protocol)
+				result.Reason = "host-timeout"
+			} else if err := r.waitRate(ctx, target.IP); err != nil {
+				result = rateLimitedResult(NewScanResult(target.IP, port, protocol), err)
+			} else {
+				startTime := time.Now()
+				probeCtx, cancel := r.withTimeLeft(ctx, remaining)
+				result = probe(probeCtx, target.IP, port)
+				cancel()
+
+				result.StartedAt = startTime
+				result.Duration = time.Since(startTime)
+				remaining -= result.Duration
+			}
+
+			result.Hostname = target.Hostname
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// withTimeLeft returns context which is done after timeLeft of HostTimeOut, or only when ctx is done if there is no HostTimeOut
+func (r *Requester) withTimeLeft(ctx context.Context, timeLeft time.Duration) (context.Context, context.CancelFunc) {
+	if r.HostTimeOut > 0 {
+		return context.WithTimeout(ctx, timeLeft)
+	}
+	return context.WithCancel(ctx)
+}
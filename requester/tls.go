@@ -0,0 +1,111 @@
+package requester
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// TlsInfo is the negotiated tls connection and the certificate of the server
+type TlsInfo struct {
+	Version   string
+	Cipher    string
+	Subject   string
+	SANs      []string // dns names and ip addresses
+	Issuer    string
+	Serial    string // hex
+	NotBefore time.Time
+	NotAfter  time.Time
+	KeyType   string // e.g. RSA-2048, ECDSA-P-256, Ed25519
+}
+
+type tlsProbe struct {
+	requester *Requester
+}
+
+func (p *tlsProbe) Probe(ctx context.Context, result *ScanResult) {
+	if result.Protocol != PROTOCOL_TCP {
+		return
+	}
+
+	info, err := p.requester.TlsHandshake(ctx, result.IP, result.Hostname, result.Port)
+	if err != nil {
+		return
+	}
+
+	result.Tls = info
+}
+
+// TlsHandshake does tls handshake with the port and returns the certificate without verifying it,
+// hostname is sent as SNI if it's not empty
+func (r *Requester) TlsHandshake(ctx context.Context, ip string, hostname string, port int) (*TlsInfo, error) {
+	if err := r.waitRate(ctx, ip); err != nil {
+		return nil, err
+	}
+
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: r.TimeOut},
+		Config: &tls.Config{
+			ServerName:         hostname,
+			InsecureSkipVerify: true, // the certificate is collected, not trusted
+			MinVersion:         tls.VersionTLS10,
+		},
+	}
+
+	// the dialer timeout is for connecting only, this covers the handshake too
+	ctx, cancel := context.WithTimeout(ctx, r.TimeOut)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no certificate received")
+	}
+
+	return newTlsInfo(state, state.PeerCertificates[0]), nil
+}
+
+func newTlsInfo(state tls.ConnectionState, cert *x509.Certificate) *TlsInfo {
+	info := &TlsInfo{
+		Version:   tls.VersionName(state.Version),
+		Cipher:    tls.CipherSuiteName(state.CipherSuite),
+		Subject:   cert.Subject.String(),
+		SANs:      append([]string{}, cert.DNSNames...),
+		Issuer:    cert.Issuer.String(),
+		Serial:    cert.SerialNumber.Text(16),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		KeyType:   publicKeyType(cert),
+	}
+
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+
+	return info
+}
+
+func publicKeyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+
+	return cert.PublicKeyAlgorithm.String()
+}
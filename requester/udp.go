@@ -0,0 +1,74 @@
+package requester
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	udpReadBufferSize = 1500
+)
+
+// Udp checks if udp port is open by sending a datagram and waiting for the reply,
+// the datagram is a valid request for well known ports (dns, ntp, snmp) and empty for the others
+// . reply received means open
+// . icmp port unreachable (reported as refused) means closed
+// . no reply means open|filtered, the port may be open but ignoring the datagram
+// the caller waits for RateLimiter
+func (r *Requester) Udp(ctx context.Context, ip string, port int) *ScanResult {
+	result := NewScanResult(ip, port, PROTOCOL_UDP)
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	dialer := net.Dialer{Timeout: r.TimeOut}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		result.State, result.Reason = dialErrorToPortState(err)
+		if result.State == PORT_STATE_ERROR {
+			result.SetError(err)
+		}
+		return result
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(r.TimeOut)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	startTime := time.Now()
+	if _, err = conn.Write(udpPayload(port)); err != nil {
+		result.SetError(err)
+		return result
+	}
+
+	buffer := make([]byte, udpReadBufferSize)
+	_, err = conn.Read(buffer)
+	rtt := time.Since(startTime)
+	defer func() {
+		if result.IsAnswered() {
+			result.RTT = rtt
+		}
+	}()
+
+	if err == nil {
+		result.State = PORT_STATE_OPEN
+		result.Reason = "udp-response"
+		return result
+	}
+
+	result.State, result.Reason = dialErrorToPortState(err)
+	if result.Reason == "conn-refused" {
+		result.Reason = "port-unreach"
+	}
+	if result.Reason == "no-response" {
+		result.State = PORT_STATE_OPEN_FILTERED
+	}
+	if result.State == PORT_STATE_ERROR {
+		result.SetError(err)
+	}
+
+	return result
+}
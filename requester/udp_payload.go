@@ -0,0 +1,46 @@
+package requester
+
+// udp services usually ignore a datagram they do not understand,
+// so a valid request is sent to get a reply from the well known ports
+var udpPayloads = map[int][]byte{
+	// dns, query version.bind TXT CH
+	53: {
+		0x13, 0x37, // id
+		0x01, 0x00, // standard query, recursion desired
+		0x00, 0x01, // questions
+		0x00, 0x00, // answers
+		0x00, 0x00, // authorities
+		0x00, 0x00, // additionals
+		0x07, 'v', 'e', 'r', 's', 'i', 'o', 'n',
+		0x04, 'b', 'i', 'n', 'd',
+		0x00,
+		0x00, 0x10, // TXT
+		0x00, 0x03, // CH
+	},
+
+	// ntp, version 4 client request
+	123: append([]byte{0xe3}, make([]byte, 47)...),
+
+	// snmp v1, get-request sysDescr.0 with "public" community
+	161: {
+		0x30, 0x29, // sequence
+		0x02, 0x01, 0x00, // version 1
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', // community
+		0xa0, 0x1c, // get-request
+		0x02, 0x04, 0x13, 0x37, 0x13, 0x37, // request id
+		0x02, 0x01, 0x00, // error status
+		0x02, 0x01, 0x00, // error index
+		0x30, 0x0e, // variable bindings
+		0x30, 0x0c,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, // 1.3.6.1.2.1.1.1.0
+		0x05, 0x00, // null
+	},
+}
+
+// udpPayload returns the datagram to send to the port, empty for unknown port
+func udpPayload(port int) []byte {
+	if payload, ok := udpPayloads[port]; ok {
+		return payload
+	}
+	return []byte{}
+}
@@ -0,0 +1,127 @@
+package threadman
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// idle buckets are removed when a KeyedTokenBucket has more buckets than this
+	keyedBucketPruneSize = 4096
+)
+
+// TokenBucket limits the rate of something (e.g. probes) shared by every worker,
+// a taken token which is not available yet is a debt which the taker waits for
+type TokenBucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// NewTokenBucket creates TokenBucket which allows rate tokens per second and burst tokens at once (at least 1)
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token and waits until it's available, error is returned if ctx is done first
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token and returns how long until it's available
+func (b *TokenBucket) reserve() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.refill(time.Now())
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// isIdle returns true if the bucket is full, which is the same as a new bucket
+func (b *TokenBucket) isIdle(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// KeyedTokenBucket is a TokenBucket for every key (e.g. per host), created on demand
+type KeyedTokenBucket struct {
+	rate    float64
+	burst   int
+	buckets map[string]*TokenBucket
+	mutex   sync.Mutex
+}
+
+func NewKeyedTokenBucket(rate float64, burst int) *KeyedTokenBucket {
+	return &KeyedTokenBucket{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*TokenBucket),
+	}
+}
+
+// Wait takes a token of the key and waits until it's available, error is returned if ctx is done first
+func (k *KeyedTokenBucket) Wait(ctx context.Context, key string) error {
+	return k.bucket(key).Wait(ctx)
+}
+
+func (k *KeyedTokenBucket) bucket(key string) *TokenBucket {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if bucket, ok := k.buckets[key]; ok {
+		return bucket
+	}
+
+	// keep memory bounded on large sweeps, idle bucket is recreated when needed
+	if len(k.buckets) >= keyedBucketPruneSize {
+		now := time.Now()
+		for bucketKey, bucket := range k.buckets {
+			if bucket.isIdle(now) {
+				delete(k.buckets, bucketKey)
+			}
+		}
+	}
+
+	bucket := NewTokenBucket(k.rate, k.burst)
+	k.buckets[key] = bucket
+
+	return bucket
+}
@@ -0,0 +1,115 @@
+package threadman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		takes int
+		delay time.Duration // delay of the last take
+	}{
+		{"within burst", 10, 3, 3, 0},
+		{"first debt", 10, 3, 4, 100 * time.Millisecond},
+		{"third debt", 10, 3, 6, 300 * time.Millisecond},
+		{"burst at least 1", 2, 0, 2, 500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := NewTokenBucket(test.rate, test.burst)
+
+			var delay time.Duration
+			for i := 0; i < test.takes; i++ {
+				delay = bucket.reserve()
+			}
+
+			// a little time passes between the takes, which is refilled
+			if delay > test.delay || delay < test.delay-10*time.Millisecond {
+				t.Errorf("reserve() after %d takes = %v, want about %v", test.takes, delay, test.delay)
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		tokens  float64
+	}{
+		{"partial", 200 * time.Millisecond, 2},
+		{"full", time.Second, 5},
+		{"capped by burst", time.Minute, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bucket := NewTokenBucket(10, 5)
+			bucket.tokens = 0
+
+			bucket.refill(bucket.last.Add(test.elapsed))
+			if bucket.tokens < test.tokens-0.001 || bucket.tokens > test.tokens+0.001 {
+				t.Errorf("tokens after %v = %f, want %f", test.elapsed, bucket.tokens, test.tokens)
+			}
+		})
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	bucket := NewTokenBucket(1, 1)
+	if err := bucket.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() of full bucket error: %v", err)
+	}
+
+	// the next token is a second later, ctx is done first
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() of empty bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestKeyedTokenBucket(t *testing.T) {
+	k := NewKeyedTokenBucket(1, 1)
+
+	if k.bucket("10.0.0.1") != k.bucket("10.0.0.1") {
+		t.Errorf("bucket of the same key differs")
+	}
+
+	// every key has its own burst
+	for _, key := range []string{"10.0.0.1", "10.0.0.2"} {
+		if delay := k.bucket(key).reserve(); delay != 0 {
+			t.Errorf("reserve() of %s = %v, want 0", key, delay)
+		}
+	}
+	if delay := k.bucket("10.0.0.1").reserve(); delay <= 0 {
+		t.Errorf("second reserve() of 10.0.0.1 = %v, want a delay", delay)
+	}
+}
+
+func TestKeyedTokenBucketPrune(t *testing.T) {
+	k := NewKeyedTokenBucket(1, 1)
+
+	busy := k.bucket("busy")
+	busy.reserve()
+	for i := 1; i < keyedBucketPruneSize; i++ {
+		k.bucket(fmt.Sprintf("idle-%d", i))
+	}
+
+	// idle buckets are removed, the busy one keeps its debt
+	k.bucket("new")
+	if len(k.buckets) != 2 {
+		t.Errorf("buckets after prune = %d, want 2", len(k.buckets))
+	}
+	if k.bucket("busy") != busy {
+		t.Errorf("busy bucket is pruned")
+	}
+}
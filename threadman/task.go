@@ -4,4 +4,9 @@ type Task struct {
 	ID     int
 	Func   func() interface{}
 	Result interface{}
+
+	// Data is anything the caller need to keep with the task (e.g. input of Func)
+	Data interface{}
+	// Err is set when Func panics, Result is nil in that case
+	Err error
 }
@@ -1,6 +1,7 @@
 package threadman
 
 import (
+	"fmt"
 	"idie/typed"
 	"sync"
 	"sync/atomic"
@@ -18,6 +19,9 @@ const (
 
 type Option func(*Threadman)
 
+// TaskSource returns the next task to run (ID is assigned by Threadman), ok is false when there is no task left
+type TaskSource func() (task *Task, ok bool)
+
 type Threadman struct {
 	//public
 	ID          int
@@ -29,18 +33,23 @@ type Threadman struct {
 	stopping bool
 
 	standbyTasks typed.Slice // []func() interface{}
+	taskSource   TaskSource
 
 	standByCounter atomic.Uint64
 	runningCounter atomic.Uint64
 	doneCounter    atomic.Uint64
+	errorCounter   atomic.Uint64
 
 	taskCh         chan *Task
 	closing        chan struct{}
 	workerLimitter chan struct{}
 	mutex          sync.Mutex
 	wg             sync.WaitGroup
+	dispatcherWg   sync.WaitGroup
 
 	seqTaskID int
+	seqMutex  sync.Mutex
+	requeueWg sync.WaitGroup
 }
 
 func NewThreadman(fields ...Option) *Threadman {
@@ -81,14 +90,12 @@ func WithWorkerLimit(limit int) Option {
 }
 
 func (t *Threadman) worker(tParam *Task) {
-	t.wg.Add(1)
-
 	defer func() {
 		<-t.workerLimitter // release some space in workerLimitter
 		t.wg.Done()
 	}()
 
-	tParam.Result = tParam.Func()
+	t.runTask(tParam)
 	t.runningCounter.Add(^uint64(0))
 	t.doneCounter.Add(1)
 
@@ -100,6 +107,19 @@ func (t *Threadman) worker(tParam *Task) {
 
 }
 
+// runTask runs Func of the task, panic is recovered into Err so a single task can not kill the process
+func (t *Threadman) runTask(tParam *Task) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			tParam.Result = nil
+			tParam.Err = fmt.Errorf("task %d panic: %v", tParam.ID, recovered)
+			t.errorCounter.Add(1)
+		}
+	}()
+
+	tParam.Result = tParam.Func()
+}
+
 func (t *Threadman) prepareStandbyRun() {
 	if t.WorkerLimit < 1 {
 		t.WorkerLimit = workerLimit
@@ -128,7 +148,10 @@ func (t *Threadman) StandbyRun() {
 	t.running = true
 	t.prepareStandbyRun()
 
+	t.dispatcherWg.Add(1)
 	go func() {
+		defer t.dispatcherWg.Done()
+
 		for {
 			select {
 			case <-t.closing:
@@ -145,6 +168,7 @@ func (t *Threadman) StandbyRun() {
 					t.standByCounter.Add(^uint64(0))
 					t.runningCounter.Add(1)
 
+					t.wg.Add(1)
 					go t.worker(task)
 				}
 			}
@@ -168,6 +192,37 @@ func (t *Threadman) StandbyRun() {
 		t.standbyTasks.Clear()
 	}()
 
+	if t.taskSource != nil {
+		t.dispatcherWg.Add(1)
+		go t.runTaskSource()
+	}
+}
+
+// runTaskSource pulls task from taskSource only when there is a free worker,
+// so no more than WorkerLimit tasks are kept in memory
+func (t *Threadman) runTaskSource() {
+	defer t.dispatcherWg.Done()
+
+	for {
+		select {
+		case <-t.closing:
+			return
+		case t.workerLimitter <- struct{}{}:
+		}
+
+		task, ok := t.taskSource()
+		if !ok {
+			<-t.workerLimitter
+			return
+		}
+
+		t.assignTaskID(task)
+		t.decrementStandByCounter()
+		t.runningCounter.Add(1)
+
+		t.wg.Add(1)
+		go t.worker(task)
+	}
 }
 
 func (t *Threadman) Stop() {
@@ -183,11 +238,13 @@ func (t *Threadman) Stop() {
 
 	t.stopping = true
 	go func() {
-		t.closing <- struct{}{}
+		// close to notify every dispatcher, then wait for them before closing the channels they use
+		close(t.closing)
+		t.dispatcherWg.Wait()
+		t.requeueWg.Wait()
 		t.wg.Wait()
 		close(t.workerLimitter)
 		close(t.taskCh)
-		close(t.closing)
 		t.running = false
 		t.stopping = false
 
@@ -199,16 +256,33 @@ func (t *Threadman) Stop() {
 	}()
 }
 
-func (t *Threadman) AddTask(task func() interface{}) {
+// SetTaskSource sets source of tasks which is pulled on demand after StandbyRun,
+// use it instead of AddTask when there are too many tasks to be kept in memory
+// . remember to add count of the tasks with AddStandByCounter
+func (t *Threadman) SetTaskSource(source TaskSource) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.taskSource = source
+}
+
+func (t *Threadman) assignTaskID(task *Task) {
+	t.seqMutex.Lock()
+	defer t.seqMutex.Unlock()
+
 	if t.seqTaskID < 1 {
 		t.seqTaskID = 1
 	}
 
+	task.ID = t.seqTaskID
+	t.seqTaskID++
+}
+
+func (t *Threadman) AddTask(task func() interface{}) {
 	taskCreated := &Task{
-		ID:   t.seqTaskID,
 		Func: task,
 	}
-	t.seqTaskID++
+	t.assignTaskID(taskCreated)
 
 	if t.running {
 		go func() {
@@ -224,6 +298,42 @@ func (t *Threadman) AddTask(task func() interface{}) {
 	}
 }
 
+// Requeue runs the task after delay (e.g. to retry it with backoff), no worker is blocked while waiting,
+// false is returned if Threadman is not running, the task is dropped if Threadman is stopped before the delay is over
+func (t *Threadman) Requeue(task *Task, delay time.Duration) bool {
+	if !t.running || t.stopping {
+		return false
+	}
+	select {
+	case <-t.closing:
+		return false
+	default:
+	}
+
+	task.Result = nil
+	task.Err = nil
+	t.assignTaskID(task)
+	t.standByCounter.Add(1)
+
+	t.requeueWg.Add(1)
+	go func() {
+		defer t.requeueWg.Done()
+
+		select {
+		case <-t.closing:
+			return
+		case <-time.After(delay):
+		}
+
+		select {
+		case <-t.closing:
+		case t.taskCh <- task:
+		}
+	}()
+
+	return true
+}
+
 func (t *Threadman) IsRunning() bool {
 	return t.running
 }
@@ -244,6 +354,24 @@ func (t *Threadman) GetDoneCounter() uint64 {
 	return t.doneCounter.Load()
 }
 
+// GetErrorCounter returns count of tasks which panic
+func (t *Threadman) GetErrorCounter() uint64 {
+	return t.errorCounter.Load()
+}
+
 func (t *Threadman) AddStandByCounter(i uint64) {
-	t.doneCounter.Add(i)
+	t.standByCounter.Add(i)
+}
+
+// decrease standByCounter without going below zero
+func (t *Threadman) decrementStandByCounter() {
+	for {
+		current := t.standByCounter.Load()
+		if current == 0 {
+			return
+		}
+		if t.standByCounter.CompareAndSwap(current, current-1) {
+			return
+		}
+	}
 }
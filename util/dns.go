@@ -0,0 +1,122 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	resolveTimeOut = 10 * time.Second
+)
+
+// ResolveError is a hostname target which can not be resolved
+type ResolveError struct {
+	Hostname string
+	Err      error
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("unable to resolve %s: %v", e.Hostname, e.Err)
+}
+
+// NewResolver creates resolver which uses dns server at address (format: 1.1.1.1 or 1.1.1.1:53),
+// system resolver is used if address is empty
+func NewResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// ResolveTargetRanges resolves A and AAAA records of hostname targets, up to workers hostnames at the same time,
+// hostname which can not be resolved stays empty and is returned as ResolveError (in the order of ranges)
+func ResolveTargetRanges(ranges []TargetRange, resolver *net.Resolver, workers int) []*ResolveError {
+	var hosts []*hostRange
+	for _, targetRange := range ranges {
+		if host, ok := targetRange.(*hostRange); ok && len(host.ips) == 0 {
+			hosts = append(hosts, host)
+		}
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	errs := make([]error, len(hosts))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(hosts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// every worker writes only its own index
+			for index := range indexes {
+				hosts[index].ips, errs[index] = resolveHostname(resolver, hosts[index].hostname)
+			}
+		}()
+	}
+	for index := range hosts {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	var resolveErrors []*ResolveError
+	for index, err := range errs {
+		if err != nil {
+			resolveErrors = append(resolveErrors, &ResolveError{Hostname: hosts[index].hostname, Err: err})
+		}
+	}
+
+	return resolveErrors
+}
+
+func resolveHostname(resolver *net.Resolver, hostname string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeOut)
+	defer cancel()
+
+	addresses, err := resolver.LookupIP(ctx, "ip", hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, address := range addresses {
+		ip := normalizeIP(address)
+		if ip == nil {
+			continue
+		}
+
+		duplicate := false
+		for _, resolved := range ips {
+			if resolved.Equal(ip) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			ips = append(ips, ip)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found")
+	}
+
+	return ips, nil
+}
@@ -1,41 +1,37 @@
 package util
 
-import "os"
+import (
+	"fmt"
+	"os"
+)
 
 func IsFileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
 	return !os.IsNotExist(err)
 }
 
-func CreateFile(filePath string) *os.File {
+func CreateFile(filePath string) (*os.File, error) {
 	if IsFileExists(filePath) {
-		return nil
+		return nil, fmt.Errorf("file %s already exists", filePath)
 	}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		panic(err)
-	}
-
-	return file
+	return os.Create(filePath)
 }
 
-func OpenFileOrCreate(filePath string) *os.File {
+func OpenFileOrCreate(filePath string) (*os.File, error) {
 	if !IsFileExists(filePath) {
 		return CreateFile(filePath)
 	}
 
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
-	if err != nil {
-		panic(err)
-	}
+	return os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, os.ModeAppend)
+}
 
-	return file
+// TruncateOrCreate opens file for writing from the beginning, the existing content is removed
+func TruncateOrCreate(filePath string) (*os.File, error) {
+	return os.OpenFile(filePath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0666)
 }
 
-func WriteStringToFile(file *os.File, s string) {
+func WriteStringToFile(file *os.File, s string) error {
 	_, err := file.WriteString(s)
-	if err != nil {
-		panic(err)
-	}
+	return err
 }
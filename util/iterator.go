@@ -0,0 +1,361 @@
+package util
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+)
+
+// ScanIterator iterates over the targets × ports in batches without expanding them,
+// a batch has at most hostBatch ip addresses and portBatch ports,
+// duplicate ip address (already in previous target) and excluded ip address are skipped
+type ScanIterator struct {
+	targets  []TargetRange
+	excludes []TargetRange
+	ports    []int
+
+	hostBatch int
+	portBatch int
+	hostsOnly bool // every batch has no port, see NewHostIterator
+
+	permutation *Permutation // order of the batches, nil for sequential, see Randomize
+
+	// rangeOffsets[i] is the index of the first ip address of targets[i]
+	rangeOffsets []uint64
+	hostLen      uint64
+
+	// lookup of duplicate and excluded ip address, built once by indexRanges
+	// . firstPoints is the first target index of every ip address of point ranges (single ip, hostname, list)
+	// . earlierOverlaps[i] is the earlier non point targets which may contain ip address of targets[i]
+	// . excludeOverlaps[i] is the non point excludes which may contain ip address of targets[i]
+	firstPoints     map[ipKey]int
+	excludePoints   map[ipKey]bool
+	earlierOverlaps [][]int
+	excludeOverlaps [][]int
+
+	cursor uint64
+	mutex  sync.Mutex
+}
+
+// NewScanIterator creates ScanIterator, hostBatch or portBatch less than 1 means everything in a batch
+func NewScanIterator(targets []TargetRange, excludes []TargetRange, ports []int, hostBatch int, portBatch int) *ScanIterator {
+	it := &ScanIterator{
+		targets:   targets,
+		excludes:  excludes,
+		ports:     ports,
+		hostBatch: hostBatch,
+		portBatch: portBatch,
+	}
+
+	for _, target := range targets {
+		it.rangeOffsets = append(it.rangeOffsets, it.hostLen)
+		it.hostLen += target.Len()
+	}
+
+	it.indexRanges()
+
+	if it.hostBatch < 1 {
+		it.hostBatch = int(it.hostLen)
+	}
+	if it.portBatch < 1 {
+		it.portBatch = len(ports)
+	}
+
+	return it
+}
+
+// NewHostIterator creates ScanIterator which iterates over the targets only, the ports of every batch is nil
+func NewHostIterator(targets []TargetRange, excludes []TargetRange, hostBatch int) *ScanIterator {
+	it := NewScanIterator(targets, excludes, nil, hostBatch, 0)
+	it.hostsOnly = true
+
+	return it
+}
+
+// Randomize makes Next return the batches in the order of a permutation by seed instead of sequentially,
+// for batches of a single ip address and port every (ip address, port) is in random order,
+// it must be called before Next
+func (it *ScanIterator) Randomize(seed uint64) {
+	it.permutation = NewPermutation(it.Len(), seed)
+}
+
+// Len returns count of batches including the skipped ones
+func (it *ScanIterator) Len() uint64 {
+	return it.hostChunkLen() * it.portChunkLen()
+}
+
+// Next returns ip addresses and ports of the next batch, ok is false when there is nothing left
+// safe to be called from multiple goroutine
+func (it *ScanIterator) Next() (ips []net.IP, ports []int, ok bool) {
+	it.mutex.Lock()
+	defer it.mutex.Unlock()
+
+	portChunkLen := it.portChunkLen()
+	for it.cursor < it.Len() {
+		index := it.cursor
+		it.cursor++
+		if it.permutation != nil {
+			index = it.permutation.At(index)
+		}
+
+		ips = it.hostChunk(index / portChunkLen)
+		if len(ips) == 0 {
+			continue
+		}
+
+		return ips, it.portChunk(index % portChunkLen), true
+	}
+
+	return nil, nil, false
+}
+
+// Count counts ip addresses which will be scanned, ip addresses which are excluded and the batches,
+// this iterates every ip address, but not the ports
+func (it *ScanIterator) Count() (hosts uint64, excluded uint64, batches uint64) {
+	for chunk := uint64(0); chunk < it.hostChunkLen(); chunk++ {
+		start, end := it.hostChunkBounds(chunk)
+
+		chunkHosts := uint64(0)
+		for index := start; index < end; index++ {
+			_, skipReason := it.host(index)
+			if skipReason == skipReasonExcluded {
+				excluded++
+			}
+			if skipReason == skipReasonNone {
+				chunkHosts++
+			}
+		}
+
+		hosts += chunkHosts
+		if chunkHosts > 0 {
+			batches += it.portChunkLen()
+		}
+	}
+
+	return
+}
+
+func (it *ScanIterator) hostChunkLen() uint64 {
+	if it.hostLen == 0 {
+		return 0
+	}
+	return (it.hostLen + uint64(it.hostBatch) - 1) / uint64(it.hostBatch)
+}
+
+func (it *ScanIterator) portChunkLen() uint64 {
+	if it.hostsOnly {
+		return 1
+	}
+	if len(it.ports) == 0 {
+		return 0
+	}
+	return uint64((len(it.ports) + it.portBatch - 1) / it.portBatch)
+}
+
+func (it *ScanIterator) hostChunkBounds(chunk uint64) (start uint64, end uint64) {
+	start = chunk * uint64(it.hostBatch)
+	end = start + uint64(it.hostBatch)
+	if end > it.hostLen {
+		end = it.hostLen
+	}
+	return
+}
+
+func (it *ScanIterator) hostChunk(chunk uint64) []net.IP {
+	var ips []net.IP
+
+	start, end := it.hostChunkBounds(chunk)
+	for index := start; index < end; index++ {
+		if ip, skipReason := it.host(index); skipReason == skipReasonNone {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+func (it *ScanIterator) portChunk(chunk uint64) []int {
+	if it.hostsOnly {
+		return nil
+	}
+
+	start := int(chunk) * it.portBatch
+	end := start + it.portBatch
+	if end > len(it.ports) {
+		end = len(it.ports)
+	}
+	return it.ports[start:end]
+}
+
+// ENUM skipReason
+// do not use iota, to make it more readable
+const (
+	skipReasonNone      = 0
+	skipReasonDuplicate = 1
+	skipReasonExcluded  = 2
+)
+
+// host returns ip address at host index and the reason if it should be skipped
+func (it *ScanIterator) host(index uint64) (net.IP, int) {
+	// the last range which starts at or before index
+	i := sort.Search(len(it.rangeOffsets), func(i int) bool {
+		return it.rangeOffsets[i] > index
+	}) - 1
+
+	ip := it.targets[i].At(index - it.rangeOffsets[i])
+	key := newIPKey(ip)
+
+	// same ip address on the previous targets is already scanned
+	if first, ok := it.firstPoints[key]; ok && first < i {
+		return ip, skipReasonDuplicate
+	}
+	for _, j := range it.earlierOverlaps[i] {
+		if it.targets[j].Contains(ip) {
+			return ip, skipReasonDuplicate
+		}
+	}
+
+	// never touch excluded ip address
+	if it.excludePoints[key] {
+		return ip, skipReasonExcluded
+	}
+	for _, j := range it.excludeOverlaps[i] {
+		if it.excludes[j].Contains(ip) {
+			return ip, skipReasonExcluded
+		}
+	}
+
+	return ip, skipReasonNone
+}
+
+// ipKey is ip address as map key, ipv4 is mapped to ipv6 so both families are in the same order
+type ipKey [net.IPv6len]byte
+
+func newIPKey(ip net.IP) ipKey {
+	var key ipKey
+	copy(key[:], ip.To16())
+	return key
+}
+
+func (k ipKey) compare(other ipKey) int {
+	return bytes.Compare(k[:], other[:])
+}
+
+// rangeBounds is the lowest and highest ip address of a target or exclude range
+type rangeBounds struct {
+	index   int // index in targets or excludes
+	exclude bool
+	point   bool // ip addresses are in firstPoints or excludePoints, see isPointRange
+	first   ipKey
+	last    ipKey
+}
+
+// point range has a few ip addresses which are not continuous, so they are looked up one by one
+func isPointRange(r TargetRange) bool {
+	switch r.(type) {
+	case *hostRange, *ipListRange:
+		return true
+	}
+	return r.Len() == 1
+}
+
+// indexRanges builds the lookup of duplicate and excluded ip address,
+// so host does not need to check every target and exclude (thousands of lines of a targets file)
+// . ip addresses of point ranges are put in a map
+// . the other ranges are checked only for the ranges they overlap, found by sweeping the ranges sorted by their first ip address
+func (it *ScanIterator) indexRanges() {
+	it.firstPoints = make(map[ipKey]int)
+	it.excludePoints = make(map[ipKey]bool)
+	it.earlierOverlaps = make([][]int, len(it.targets))
+	it.excludeOverlaps = make([][]int, len(it.targets))
+
+	var bounds []rangeBounds
+	for i, target := range it.targets {
+		if b, ok := it.indexRange(target, i, false); ok {
+			bounds = append(bounds, b)
+		}
+	}
+	for i, exclude := range it.excludes {
+		if b, ok := it.indexRange(exclude, i, true); ok {
+			bounds = append(bounds, b)
+		}
+	}
+
+	sort.SliceStable(bounds, func(i, j int) bool {
+		return bounds[i].first.compare(bounds[j].first) < 0
+	})
+
+	// every active range starts before current, so it overlaps current if it does not end before current starts
+	var active []rangeBounds
+	for _, current := range bounds {
+		kept := active[:0]
+		for _, other := range active {
+			if other.last.compare(current.first) < 0 {
+				continue
+			}
+			kept = append(kept, other)
+
+			it.addOverlap(current, other)
+			it.addOverlap(other, current)
+		}
+		active = append(kept, current)
+	}
+}
+
+// indexRange puts ip addresses of point range to the maps and returns bounds of the range, ok is false for empty range
+func (it *ScanIterator) indexRange(r TargetRange, index int, exclude bool) (b rangeBounds, ok bool) {
+	if r.Len() == 0 {
+		return
+	}
+
+	// ip address of non point range is in ascending order
+	b = rangeBounds{
+		index:   index,
+		exclude: exclude,
+		point:   isPointRange(r),
+		first:   newIPKey(r.At(0)),
+		last:    newIPKey(r.At(r.Len() - 1)),
+	}
+	if ipr, isIPRange := r.(*ipRange); isIPRange {
+		// length of huge ipv6 exclude is saturated, so At(Len-1) is not the end
+		b.last = newIPKey(ipr.end)
+	}
+	if !b.point {
+		return b, true
+	}
+
+	for i := uint64(0); i < r.Len(); i++ {
+		key := newIPKey(r.At(i))
+		if key.compare(b.first) < 0 {
+			b.first = key
+		}
+		if key.compare(b.last) > 0 {
+			b.last = key
+		}
+
+		if exclude {
+			it.excludePoints[key] = true
+		} else if _, found := it.firstPoints[key]; !found {
+			it.firstPoints[key] = index
+		}
+	}
+
+	return b, true
+}
+
+// addOverlap records other as a range to check for ip address of target, point ranges are in the maps already
+func (it *ScanIterator) addOverlap(target rangeBounds, other rangeBounds) {
+	if target.exclude || other.point {
+		return
+	}
+
+	if other.exclude {
+		it.excludeOverlaps[target.index] = append(it.excludeOverlaps[target.index], other.index)
+		return
+	}
+
+	if other.index < target.index {
+		it.earlierOverlaps[target.index] = append(it.earlierOverlaps[target.index], other.index)
+	}
+}
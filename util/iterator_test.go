@@ -0,0 +1,137 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func mustParseTargetSpecs(t *testing.T, specs ...string) []TargetRange {
+	t.Helper()
+
+	ranges, err := ParseTargetSpecs(specs)
+	if err != nil {
+		t.Fatalf("ParseTargetSpecs(%v) error: %v", specs, err)
+	}
+	return ranges
+}
+
+// drain returns every (ip address, port) of the iterator and the count of batches
+func drain(t *testing.T, it *ScanIterator, hostBatch int, portBatch int) (map[string]int, uint64) {
+	t.Helper()
+
+	pairs := make(map[string]int)
+	batches := uint64(0)
+	for {
+		ips, ports, ok := it.Next()
+		if !ok {
+			break
+		}
+		batches++
+
+		if len(ips) == 0 || len(ips) > hostBatch {
+			t.Fatalf("batch has %d ip addresses, want 1-%d", len(ips), hostBatch)
+		}
+		if portBatch > 0 && len(ports) > portBatch {
+			t.Fatalf("batch has %d ports, want at most %d", len(ports), portBatch)
+		}
+
+		for _, ip := range ips {
+			for _, port := range ports {
+				pairs[fmt.Sprintf("%s:%d", ip, port)]++
+			}
+		}
+	}
+	return pairs, batches
+}
+
+func TestScanIteratorCountNext(t *testing.T) {
+	tests := []struct {
+		name      string
+		targets   []string
+		excludes  []string
+		ports     []int
+		hostBatch int
+		portBatch int
+		hosts     uint64
+		excluded  uint64
+	}{
+		{"single", []string{"10.0.0.1"}, nil, []int{80}, 1, 1, 1, 0},
+		{"cidr", []string{"10.0.0.0/28"}, nil, []int{22, 80, 443}, 1, 1, 16, 0},
+		{"batches", []string{"10.0.0.0/28"}, nil, []int{22, 80, 443}, 5, 2, 16, 0},
+		{"everything in a batch", []string{"10.0.0.0/28"}, nil, []int{22, 80, 443}, 0, 0, 16, 0},
+		{"duplicate", []string{"10.0.0.0/30", "10.0.0.2", "10.0.0.1-10.0.0.5"}, nil, []int{80}, 1, 1, 6, 0},
+		{"exclude", []string{"10.0.0.0/28"}, []string{"10.0.0.4-10.0.0.7", "10.0.0.9"}, []int{80, 443}, 3, 1, 11, 5},
+		{"exclude everything", []string{"10.0.0.0/30"}, []string{"10.0.0.0/24"}, []int{80}, 1, 1, 0, 4},
+		{"octet", []string{"10.0.1-2.1-3", "10.0.1.0/30"}, []string{"10.0.2.2"}, []int{80}, 2, 1, 6, 1},
+		{"ipv6", []string{"2001:db8::/126", "10.0.0.1"}, []string{"2001:db8::/64"}, []int{80}, 1, 1, 1, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targets := mustParseTargetSpecs(t, test.targets...)
+			excludes := mustParseTargetSpecs(t, test.excludes...)
+
+			hosts, excluded, batches := NewScanIterator(targets, excludes, test.ports, test.hostBatch, test.portBatch).Count()
+			if hosts != test.hosts || excluded != test.excluded {
+				t.Errorf("Count() = %d hosts %d excluded, want %d hosts %d excluded", hosts, excluded, test.hosts, test.excluded)
+			}
+
+			hostBatch := test.hostBatch
+			if hostBatch < 1 {
+				hostBatch = int(^uint(0) >> 1)
+			}
+			pairs, nextBatches := drain(t, NewScanIterator(targets, excludes, test.ports, test.hostBatch, test.portBatch), hostBatch, test.portBatch)
+			if nextBatches != batches {
+				t.Errorf("Next() returns %d batches, Count() = %d", nextBatches, batches)
+			}
+			if uint64(len(pairs)) != hosts*uint64(len(test.ports)) {
+				t.Errorf("Next() returns %d (ip, port), want %d", len(pairs), hosts*uint64(len(test.ports)))
+			}
+			for pair, count := range pairs {
+				if count != 1 {
+					t.Errorf("Next() returns %s %d times, want once", pair, count)
+				}
+			}
+		})
+	}
+}
+
+func TestScanIteratorSkipsDuplicateAndExcluded(t *testing.T) {
+	targets := mustParseTargetSpecs(t, "10.0.0.0/30", "10.0.0.3-10.0.0.4")
+	targets = append(targets, NewIPListRange("list", []net.IP{net.ParseIP("10.0.0.4"), net.ParseIP("10.0.0.9")}))
+	excludes := mustParseTargetSpecs(t, "10.0.0.1")
+
+	pairs, _ := drain(t, NewScanIterator(targets, excludes, []int{80}, 1, 1), 1, 1)
+
+	want := []string{"10.0.0.0:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80", "10.0.0.9:80"}
+	if len(pairs) != len(want) {
+		t.Errorf("Next() returns %v, want %v", pairs, want)
+	}
+	for _, pair := range want {
+		if pairs[pair] != 1 {
+			t.Errorf("Next() returns %s %d times, want once", pair, pairs[pair])
+		}
+	}
+}
+
+func TestHostIterator(t *testing.T) {
+	targets := mustParseTargetSpecs(t, "10.0.0.0/29")
+
+	it := NewHostIterator(targets, nil, 3)
+	hosts := 0
+	for {
+		ips, ports, ok := it.Next()
+		if !ok {
+			break
+		}
+		if ports != nil {
+			t.Errorf("host iterator returns ports %v, want nil", ports)
+		}
+		hosts += len(ips)
+	}
+
+	if hosts != 8 {
+		t.Errorf("host iterator returns %d ip addresses, want 8", hosts)
+	}
+}
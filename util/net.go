@@ -1,52 +1,30 @@
 package util
 
 import (
-	"fmt"
+	"bytes"
 	"net"
+	"sort"
 )
 
-func GenerateIPRange(startInput, endInput string) ([]string, error) {
-	startIP := net.ParseIP(startInput)
-	endIP := net.ParseIP(endInput)
-
-	if startIP == nil || endIP == nil {
-		return nil, fmt.Errorf("invalid IP address")
-	}
-
-	if bytesLessThan(endIP, startIP) {
-		return nil, fmt.Errorf("end IP address is less than start IP address")
-	}
-
-	var ipRange []string
-
-	for ip := startIP; !ip.Equal(endIP); incrementIP(ip) {
-		ipRange = append(ipRange, ip.String())
-	}
-
-	ipRange = append(ipRange, endIP.String())
-
-	return ipRange, nil
-}
-
-func incrementIP(ip net.IP) {
-	for j := len(ip) - 1; j >= 0; j-- {
-		ip[j]++
-		if ip[j] > 0 {
-			break
+// SortIPs sorts ip address strings by their numeric value, ipv4 before ipv6,
+// invalid ip addresses are placed last in lexical order
+func SortIPs(ips []string) {
+	sort.SliceStable(ips, func(i, j int) bool {
+		a := net.ParseIP(ips[i])
+		b := net.ParseIP(ips[j])
+
+		if a == nil || b == nil {
+			if a == nil && b == nil {
+				return ips[i] < ips[j]
+			}
+			return b == nil
 		}
-	}
-}
-
-func bytesLessThan(a, b net.IP) bool {
-	if len(a) != len(b) {
-		return false
-	}
 
-	for i := 0; i < len(a); i++ {
-		if a[i] < b[i] {
-			return true
+		isV4A, isV4B := a.To4() != nil, b.To4() != nil
+		if isV4A != isV4B {
+			return isV4A
 		}
-	}
 
-	return false
+		return bytes.Compare(a.To16(), b.To16()) < 0
+	})
 }
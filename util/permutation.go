@@ -0,0 +1,69 @@
+package util
+
+const (
+	permutationRounds = 4
+)
+
+// Permutation is a seeded bijection over [0, n) which is computed on demand, without storing the n indexes,
+// it's a balanced feistel network over the smallest 2^(2k) domain containing n, indexes falling out of [0, n) are
+// encrypted again (cycle walking) until they fall in, that happens less than 4 times on average
+type Permutation struct {
+	n        uint64
+	halfBits uint
+	halfMask uint64
+	keys     [permutationRounds]uint64
+}
+
+func NewPermutation(n uint64, seed uint64) *Permutation {
+	p := &Permutation{n: n, halfBits: 1}
+
+	// smallest 2^(2*halfBits) which is at least n
+	for p.halfBits < 32 && uint64(1)<<(2*p.halfBits) < n {
+		p.halfBits++
+	}
+	p.halfMask = uint64(1)<<p.halfBits - 1
+
+	state := seed
+	for i := range p.keys {
+		state, p.keys[i] = splitMix64(state)
+	}
+
+	return p
+}
+
+// At returns the index which index is moved to, index must be less than n
+func (p *Permutation) At(index uint64) uint64 {
+	if p.n <= 1 {
+		return index
+	}
+
+	for {
+		index = p.encrypt(index)
+		if index < p.n {
+			return index
+		}
+	}
+}
+
+func (p *Permutation) encrypt(index uint64) uint64 {
+	left := index >> p.halfBits & p.halfMask
+	right := index & p.halfMask
+
+	for _, key := range p.keys {
+		_, mixed := splitMix64(right ^ key)
+		left, right = right, left^(mixed&p.halfMask)
+	}
+
+	return left<<p.halfBits | right
+}
+
+// splitMix64 returns the next state and its well mixed output
+func splitMix64(state uint64) (uint64, uint64) {
+	state += 0x9e3779b97f4a7c15
+
+	z := state
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+
+	return state, z ^ z>>31
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPermutationIsBijection(t *testing.T) {
+	for _, n := range []uint64{0, 1, 2, 3, 4, 5, 17, 100, 255, 256, 257, 1000, 65537} {
+		for _, seed := range []uint64{0, 1, 42, ^uint64(0)} {
+			p := NewPermutation(n, seed)
+
+			seen := make([]bool, n)
+			for i := uint64(0); i < n; i++ {
+				moved := p.At(i)
+				if moved >= n {
+					t.Fatalf("NewPermutation(%d, %d).At(%d) = %d, out of range", n, seed, i, moved)
+				}
+				if seen[moved] {
+					t.Fatalf("NewPermutation(%d, %d).At(%d) = %d, already returned", n, seed, i, moved)
+				}
+				seen[moved] = true
+			}
+		}
+	}
+}
+
+func TestPermutationSeed(t *testing.T) {
+	const n = 1000
+
+	same := 0
+	for i := uint64(0); i < n; i++ {
+		if NewPermutation(n, 7).At(i) != NewPermutation(n, 7).At(i) {
+			t.Fatalf("permutation of the same seed differs at %d", i)
+		}
+		if NewPermutation(n, 7).At(i) == NewPermutation(n, 8).At(i) {
+			same++
+		}
+	}
+
+	// a permutation of another seed rarely places an index at the same position
+	if same > n/10 {
+		t.Errorf("permutations of seed 7 and 8 are the same at %d of %d indexes", same, n)
+	}
+}
+
+// pairsInOrder returns every (ip address, port) of the iterator in the order it returns them
+func pairsInOrder(it *ScanIterator) []string {
+	var pairs []string
+	for {
+		ips, ports, ok := it.Next()
+		if !ok {
+			return pairs
+		}
+		for _, ip := range ips {
+			for _, port := range ports {
+				pairs = append(pairs, fmt.Sprintf("%s:%d", ip, port))
+			}
+		}
+	}
+}
+
+func TestScanIteratorRandomize(t *testing.T) {
+	targets := mustParseTargetSpecs(t, "10.0.0.0/28", "10.0.0.8-10.0.0.20")
+	excludes := mustParseTargetSpecs(t, "10.0.0.3")
+	ports := []int{22, 80, 443}
+
+	randomized := func(seed uint64) []string {
+		it := NewScanIterator(targets, excludes, ports, 1, 1)
+		it.Randomize(seed)
+		return pairsInOrder(it)
+	}
+	sequential := pairsInOrder(NewScanIterator(targets, excludes, ports, 1, 1))
+
+	for _, seed := range []uint64{0, 1, 42, ^uint64(0)} {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			order := randomized(seed)
+
+			// the same (ip, port) as sequential, each once
+			sorted, sortedSequential := append([]string{}, order...), append([]string{}, sequential...)
+			sort.Strings(sorted)
+			sort.Strings(sortedSequential)
+			if !reflect.DeepEqual(sorted, sortedSequential) {
+				t.Fatalf("randomized iterator returns %v, want the same (ip, port) as sequential %v", order, sequential)
+			}
+
+			if reflect.DeepEqual(order, sequential) {
+				t.Errorf("randomized iterator of seed %d returns the sequential order", seed)
+			}
+			if again := randomized(seed); !reflect.DeepEqual(again, order) {
+				t.Errorf("randomized iterator of seed %d returns %v, then %v", seed, order, again)
+			}
+			if other := randomized(seed + 1); reflect.DeepEqual(other, order) {
+				t.Errorf("randomized iterator of seed %d and %d return the same order", seed, seed+1)
+			}
+		})
+	}
+}
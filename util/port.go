@@ -0,0 +1,110 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinPort = 1
+	MaxPort = 65535
+)
+
+// ParsePortList parses port list with the format of 80-90,443,25565
+// . a range can be open ended, "-1024" is 1-1024 and "60000-" is 60000-65535
+// . item prefixed with "!" is excluded from the result (e.g. 1-1024,!135-139)
+// the result keeps the order of the first appearance and has no duplicate
+func ParsePortList(portList string) ([]int, error) {
+	portList = strings.ReplaceAll(portList, " ", "")
+	if portList == "" {
+		return nil, fmt.Errorf("port list is empty")
+	}
+
+	var included []int
+	excluded := make(map[int]bool)
+
+	for i, item := range Explode(portList, ",") {
+		exclude := strings.HasPrefix(item, "!")
+		item = strings.TrimPrefix(item, "!")
+
+		start, end, err := parsePortRange(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port list item #%d %q: %v", i+1, item, err)
+		}
+
+		for port := start; port <= end; port++ {
+			if exclude {
+				excluded[port] = true
+				continue
+			}
+			included = append(included, port)
+		}
+	}
+
+	var ports []int
+	for _, port := range UniqueIntSlice(included) {
+		if !excluded[port] {
+			ports = append(ports, port)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("no port left to scan")
+	}
+
+	return ports, nil
+}
+
+func parsePortRange(item string) (start int, end int, err error) {
+	if item == "" {
+		err = fmt.Errorf("empty item")
+		return
+	}
+
+	if !strings.Contains(item, "-") {
+		start, err = parsePort(item)
+		end = start
+		return
+	}
+
+	bounds := strings.SplitN(item, "-", 2)
+
+	start = MinPort
+	if bounds[0] != "" {
+		if start, err = parsePort(bounds[0]); err != nil {
+			return
+		}
+	}
+
+	end = MaxPort
+	if bounds[1] != "" {
+		if end, err = parsePort(bounds[1]); err != nil {
+			return
+		}
+	}
+
+	if bounds[0] == "" && bounds[1] == "" {
+		err = fmt.Errorf("range without start and end")
+		return
+	}
+
+	if start > end {
+		err = fmt.Errorf("range start %d is greater than range end %d", start, end)
+	}
+
+	return
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+
+	if port < MinPort || port > MaxPort {
+		return 0, fmt.Errorf("port %d is out of range %d-%d", port, MinPort, MaxPort)
+	}
+
+	return port, nil
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortList(t *testing.T) {
+	tests := []struct {
+		portList string
+		want     []int
+	}{
+		{"80", []int{80}},
+		{"80,443", []int{80, 443}},
+		{" 80 , 443 ", []int{80, 443}},
+		{"20-23", []int{20, 21, 22, 23}},
+		{"443,80,443", []int{443, 80}},
+		{"1-5,!2-3", []int{1, 4, 5}},
+		{"!3,1-5", []int{1, 2, 4, 5}},
+		{"-3", []int{1, 2, 3}},
+		{"65533-", []int{65533, 65534, 65535}},
+	}
+
+	for _, test := range tests {
+		got, err := ParsePortList(test.portList)
+		if err != nil {
+			t.Errorf("ParsePortList(%q) error: %v", test.portList, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParsePortList(%q) = %v, want %v", test.portList, got, test.want)
+		}
+	}
+}
+
+func TestParsePortListInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"0",
+		"65536",
+		"http",
+		"80,,443",
+		"90-80",
+		"-",
+		"!80",
+		"80,!80",
+	}
+
+	for _, portList := range tests {
+		if got, err := ParsePortList(portList); err == nil {
+			t.Errorf("ParsePortList(%q) = %v, want error", portList, got)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ipv6 network is huge, scanning more than this is a mistake (/112 network), see CheckScanTargets
+	MaxIPv6RangeLen = 1 << 16
+)
+
+// TargetRange is a parsed target specification,
+// ip addresses inside the range are accessed by index so the range does not need to be expanded
+type TargetRange interface {
+	// Len returns count of ip addresses inside the range
+	Len() uint64
+	// At returns ip address at index i (0 <= i < Len)
+	At(i uint64) net.IP
+	// Contains checks if ip address is inside the range
+	Contains(ip net.IP) bool
+	// Hostname returns the hostname of the range, empty if the range is not a hostname
+	Hostname() string
+	// String returns the specification of the range
+	String() string
+}
+
+// ipRange is a continuous range of ip addresses (single ip, cidr, start-end)
+type ipRange struct {
+	spec  string
+	start net.IP
+	end   net.IP
+	len   uint64
+}
+
+// hostRange is ip addresses of a hostname, empty until resolved (see ResolveTargetRanges)
+type hostRange struct {
+	hostname string
+	ips      []net.IP
+}
+
+// ipListRange is a list of ip addresses (e.g. live hosts found by the discovery), Contains is a set lookup
+type ipListRange struct {
+	spec string
+	ips  []net.IP
+	set  map[string]bool // key is 16 bytes ip address
+}
+
+// octetRange is nmap-style ipv4 range where each octet is a range (e.g. 192.168.1-3.1-254, 10.0.0.*)
+type octetRange struct {
+	spec   string
+	octets [4][2]int // [octet index][start, end]
+}
+
+// ParseTargetSpec parses single target specification, supported formats:
+// . single ip address (10.0.0.1, 2001:db8::1)
+// . cidr (10.0.0.0/24, 2001:db8::/120)
+// . start-end range (10.0.0.1-10.0.0.50, 2001:db8::1-2001:db8::ff)
+// . nmap-style octet range, ipv4 only (10.0.1-3.1-254, 10.0.0.*)
+// . hostname (db.internal.example), resolved later with ResolveTargetRanges
+func ParseTargetSpec(spec string) (TargetRange, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("target is empty")
+	}
+
+	if strings.Contains(spec, "/") {
+		return parseCIDRSpec(spec)
+	}
+
+	if ip := net.ParseIP(spec); ip != nil {
+		return newIPRange(spec, ip, ip)
+	}
+
+	if bounds := strings.SplitN(spec, "-", 2); len(bounds) == 2 {
+		start := net.ParseIP(bounds[0])
+		end := net.ParseIP(bounds[1])
+		if start != nil && end != nil {
+			return newIPRange(spec, start, end)
+		}
+	}
+
+	if strings.Count(spec, ".") == 3 && octetSpecRegex.MatchString(spec) {
+		return parseOctetSpec(spec)
+	}
+
+	if IsValidHostname(spec) {
+		return &hostRange{hostname: strings.ToLower(strings.TrimSuffix(spec, "."))}, nil
+	}
+
+	return nil, fmt.Errorf("invalid target %q", spec)
+}
+
+var octetSpecRegex = regexp.MustCompile(`^[0-9*\-.]+$`)
+
+// ParseTargetSpecs parses multiple target specifications, see ParseTargetSpec
+func ParseTargetSpecs(specs []string) ([]TargetRange, error) {
+	var ranges []TargetRange
+
+	for _, spec := range specs {
+		targetRange, err := ParseTargetSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, targetRange)
+	}
+
+	return ranges, nil
+}
+
+// TargetRangesHostnames returns hostnames of every ip address resolved from hostname targets
+func TargetRangesHostnames(ranges []TargetRange) map[string][]string {
+	hostnames := make(map[string][]string)
+
+	for _, targetRange := range ranges {
+		hostname := targetRange.Hostname()
+		if hostname == "" {
+			continue
+		}
+
+		for i := uint64(0); i < targetRange.Len(); i++ {
+			ip := targetRange.At(i).String()
+			if !IsStringSliceContains(hostnames[ip], hostname) {
+				hostnames[ip] = append(hostnames[ip], hostname)
+			}
+		}
+	}
+
+	return hostnames
+}
+
+func parseCIDRSpec(spec string) (TargetRange, error) {
+	_, ipNet, err := net.ParseCIDR(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cidr %q", spec)
+	}
+
+	start := ipNet.IP
+	end := make(net.IP, len(start))
+	for i := range start {
+		end[i] = start[i] | ^ipNet.Mask[i]
+	}
+
+	return newIPRange(spec, start, end)
+}
+
+func parseOctetSpec(spec string) (TargetRange, error) {
+	r := &octetRange{spec: spec}
+
+	for i, octet := range Explode(spec, ".") {
+		start, end, err := parseOctet(octet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %v", spec, err)
+		}
+		r.octets[i] = [2]int{start, end}
+	}
+
+	return r, nil
+}
+
+func parseOctet(octet string) (start int, end int, err error) {
+	if octet == "*" {
+		return 0, 255, nil
+	}
+
+	bounds := strings.SplitN(octet, "-", 2)
+	if start, err = parseOctetNumber(bounds[0]); err != nil {
+		return
+	}
+
+	end = start
+	if len(bounds) == 2 {
+		if end, err = parseOctetNumber(bounds[1]); err != nil {
+			return
+		}
+	}
+
+	if start > end {
+		err = fmt.Errorf("octet range start %d is greater than range end %d", start, end)
+	}
+
+	return
+}
+
+func parseOctetNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return 0, fmt.Errorf("invalid octet %q", s)
+	}
+	return n, nil
+}
+
+func newIPRange(spec string, start net.IP, end net.IP) (*ipRange, error) {
+	start, end = normalizeIP(start), normalizeIP(end)
+	if len(start) != len(end) {
+		return nil, fmt.Errorf("invalid target %q: start and end ip address are not the same family", spec)
+	}
+
+	if bytes.Compare(start, end) > 0 {
+		return nil, fmt.Errorf("invalid target %q: end ip address is less than start ip address", spec)
+	}
+
+	// ipv6 range of /64 or larger can not be counted, the length is saturated,
+	// that's fine for exclude which is never expanded, scan target is rejected by CheckScanTargets
+	length := ^uint64(0)
+	distance := new(big.Int).Sub(new(big.Int).SetBytes(end), new(big.Int).SetBytes(start))
+	if distance.IsUint64() && distance.Uint64() < length {
+		length = distance.Uint64() + 1
+	}
+
+	return &ipRange{
+		spec:  spec,
+		start: start,
+		end:   end,
+		len:   length,
+	}, nil
+}
+
+// CheckScanTargets checks if the ranges can be scanned, ipv6 range larger than MaxIPv6RangeLen is rejected,
+// it's not checked by ParseTargetSpec since excluding a huge ipv6 network (e.g. 2001:db8::/64) is common
+func CheckScanTargets(ranges []TargetRange) error {
+	for _, targetRange := range ranges {
+		if targetRange.Len() > MaxIPv6RangeLen && targetRange.At(0).To4() == nil {
+			return fmt.Errorf("invalid target %q: ipv6 range is larger than %d addresses", targetRange.String(), MaxIPv6RangeLen)
+		}
+	}
+	return nil
+}
+
+// normalizeIP returns 4 bytes ip for ipv4, 16 bytes for ipv6
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip.To16()
+}
+
+func (r *ipRange) Len() uint64 {
+	return r.len
+}
+
+func (r *ipRange) At(i uint64) net.IP {
+	ip := make(net.IP, len(r.start))
+	copy(ip, r.start)
+
+	// add i to ip address, starting from the last byte with carry
+	carry := i
+	for j := len(ip) - 1; j >= 0 && carry > 0; j-- {
+		sum := uint64(ip[j]) + carry&0xff
+		ip[j] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+
+	return ip
+}
+
+func (r *ipRange) Contains(ip net.IP) bool {
+	ip = normalizeIP(ip)
+	if len(ip) != len(r.start) {
+		return false
+	}
+
+	return bytes.Compare(ip, r.start) >= 0 && bytes.Compare(ip, r.end) <= 0
+}
+
+func (r *ipRange) Hostname() string {
+	return ""
+}
+
+func (r *ipRange) String() string {
+	return r.spec
+}
+
+func (r *hostRange) Len() uint64 {
+	return uint64(len(r.ips))
+}
+
+func (r *hostRange) At(i uint64) net.IP {
+	return r.ips[i]
+}
+
+func (r *hostRange) Contains(ip net.IP) bool {
+	for _, hostIP := range r.ips {
+		if hostIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *hostRange) Hostname() string {
+	return r.hostname
+}
+
+func (r *hostRange) String() string {
+	return r.hostname
+}
+
+// NewIPListRange creates TargetRange of ip addresses in the given order, spec is only for String
+func NewIPListRange(spec string, ips []net.IP) TargetRange {
+	r := &ipListRange{
+		spec: spec,
+		ips:  ips,
+		set:  make(map[string]bool, len(ips)),
+	}
+
+	for _, ip := range ips {
+		r.set[string(ip.To16())] = true
+	}
+
+	return r
+}
+
+func (r *ipListRange) Len() uint64 {
+	return uint64(len(r.ips))
+}
+
+func (r *ipListRange) At(i uint64) net.IP {
+	return r.ips[i]
+}
+
+func (r *ipListRange) Contains(ip net.IP) bool {
+	return r.set[string(ip.To16())]
+}
+
+func (r *ipListRange) Hostname() string {
+	return ""
+}
+
+func (r *ipListRange) String() string {
+	return r.spec
+}
+
+func (r *octetRange) Len() uint64 {
+	length := uint64(1)
+	for _, octet := range r.octets {
+		length *= uint64(octet[1] - octet[0] + 1)
+	}
+	return length
+}
+
+func (r *octetRange) At(i uint64) net.IP {
+	ip := make(net.IP, net.IPv4len)
+
+	// the last octet changes the fastest
+	for j := len(r.octets) - 1; j >= 0; j-- {
+		size := uint64(r.octets[j][1] - r.octets[j][0] + 1)
+		ip[j] = byte(uint64(r.octets[j][0]) + i%size)
+		i /= size
+	}
+
+	return ip
+}
+
+func (r *octetRange) Contains(ip net.IP) bool {
+	ip = ip.To4()
+	if ip == nil {
+		return false
+	}
+
+	for j, octet := range r.octets {
+		if int(ip[j]) < octet[0] || int(ip[j]) > octet[1] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *octetRange) Hostname() string {
+	return ""
+}
+
+func (r *octetRange) String() string {
+	return r.spec
+}
+
+// ParseTargetFile parses target specifications from file, one specification per line,
+// empty lines are skipped and text after "#" is a comment
+func ParseTargetFile(filePath string) ([]TargetRange, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var ranges []TargetRange
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		targetRange, err := ParseTargetSpec(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filePath, lineNumber, err)
+		}
+		ranges = append(ranges, targetRange)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%d: %v", filePath, lineNumber, err)
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,136 @@
+package util
+
+import (
+	"net"
+	"testing"
+)
+
+// targetRangeIPs expands the range, only for small ranges
+func targetRangeIPs(r TargetRange) []string {
+	var ips []string
+	for i := uint64(0); i < r.Len(); i++ {
+		ips = append(ips, r.At(i).String())
+	}
+	return ips
+}
+
+func TestParseTargetSpec(t *testing.T) {
+	tests := []struct {
+		spec  string
+		len   uint64
+		first string
+		last  string
+	}{
+		{"10.0.0.1", 1, "10.0.0.1", "10.0.0.1"},
+		{"10.0.0.0/24", 256, "10.0.0.0", "10.0.0.255"},
+		{"10.0.0.77/30", 4, "10.0.0.76", "10.0.0.79"},
+		{"10.0.0.250-10.0.1.5", 12, "10.0.0.250", "10.0.1.5"},
+		{"10.0.1-3.1-254", 3 * 254, "10.0.1.1", "10.0.3.254"},
+		{"10.0.0.*", 256, "10.0.0.0", "10.0.0.255"},
+		{"2001:db8::1", 1, "2001:db8::1", "2001:db8::1"},
+		{"2001:db8::/120", 256, "2001:db8::", "2001:db8::ff"},
+		{"2001:db8::fe-2001:db8::101", 4, "2001:db8::fe", "2001:db8::101"},
+	}
+
+	for _, test := range tests {
+		r, err := ParseTargetSpec(test.spec)
+		if err != nil {
+			t.Errorf("ParseTargetSpec(%q) error: %v", test.spec, err)
+			continue
+		}
+
+		if r.Len() != test.len {
+			t.Errorf("ParseTargetSpec(%q).Len() = %d, want %d", test.spec, r.Len(), test.len)
+			continue
+		}
+		if first := r.At(0).String(); first != test.first {
+			t.Errorf("ParseTargetSpec(%q).At(0) = %s, want %s", test.spec, first, test.first)
+		}
+		if last := r.At(r.Len() - 1).String(); last != test.last {
+			t.Errorf("ParseTargetSpec(%q).At(%d) = %s, want %s", test.spec, r.Len()-1, last, test.last)
+		}
+		if !r.Contains(net.ParseIP(test.first)) || !r.Contains(net.ParseIP(test.last)) {
+			t.Errorf("ParseTargetSpec(%q) does not contain its first and last ip address", test.spec)
+		}
+		if r.Hostname() != "" {
+			t.Errorf("ParseTargetSpec(%q).Hostname() = %q, want empty", test.spec, r.Hostname())
+		}
+	}
+}
+
+func TestParseTargetSpecOctetOrder(t *testing.T) {
+	r, err := ParseTargetSpec("10.0.1-2.7-8")
+	if err != nil {
+		t.Fatalf("ParseTargetSpec error: %v", err)
+	}
+
+	want := []string{"10.0.1.7", "10.0.1.8", "10.0.2.7", "10.0.2.8"}
+	got := targetRangeIPs(r)
+	if len(got) != len(want) {
+		t.Fatalf("ip addresses = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ip addresses = %v, want %v", got, want)
+		}
+	}
+
+	if r.Contains(net.ParseIP("10.0.1.9")) || r.Contains(net.ParseIP("10.0.3.7")) || r.Contains(net.ParseIP("2001:db8::1")) {
+		t.Errorf("octet range contains ip address outside of it")
+	}
+}
+
+func TestParseTargetSpecHostname(t *testing.T) {
+	r, err := ParseTargetSpec("DB.Internal.Example.")
+	if err != nil {
+		t.Fatalf("ParseTargetSpec error: %v", err)
+	}
+	if r.Hostname() != "db.internal.example" {
+		t.Errorf("Hostname() = %q, want db.internal.example", r.Hostname())
+	}
+	if r.Len() != 0 {
+		t.Errorf("Len() = %d before resolving, want 0", r.Len())
+	}
+}
+
+func TestParseTargetSpecInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"10.0.0.0/33",
+		"10.0.0.9-10.0.0.1",
+		"10.0.0.1-2001:db8::1",
+		"10.0.300.1",
+		"10.0.5-1.1",
+		"10.0.0",
+		"10.0.1,3.1",
+		"not a host",
+	}
+
+	for _, spec := range tests {
+		if r, err := ParseTargetSpec(spec); err == nil {
+			t.Errorf("ParseTargetSpec(%q) = %v, want error", spec, r)
+		}
+	}
+}
+
+func TestCheckScanTargets(t *testing.T) {
+	// huge ipv6 network is fine to exclude, but not to scan
+	huge, err := ParseTargetSpec("2001:db8::/64")
+	if err != nil {
+		t.Fatalf("ParseTargetSpec(2001:db8::/64) error: %v", err)
+	}
+	if !huge.Contains(net.ParseIP("2001:db8::ffff:1")) {
+		t.Errorf("2001:db8::/64 does not contain 2001:db8::ffff:1")
+	}
+	if err = CheckScanTargets([]TargetRange{huge}); err == nil {
+		t.Errorf("CheckScanTargets(2001:db8::/64) = nil, want error")
+	}
+
+	targets, err := ParseTargetSpecs([]string{"2001:db8::/112", "0.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ParseTargetSpecs error: %v", err)
+	}
+	if err = CheckScanTargets(targets); err != nil {
+		t.Errorf("CheckScanTargets(2001:db8::/112, 0.0.0.0/8) error: %v", err)
+	}
+}
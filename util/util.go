@@ -1,7 +1,6 @@
 package util
 
 import (
-	"strconv"
 	"strings"
 )
 
@@ -9,23 +8,6 @@ func Explode(str string, glue string) []string {
 	return strings.Split(str, glue)
 }
 
-func ExplodeToIntSlice(str string, glue string) []int {
-	var result []int
-
-	if str == "" {
-		return result
-	}
-
-	for _, item := range Explode(str, glue) {
-		i, err := strconv.Atoi(item)
-		if err != nil {
-			continue
-		}
-		result = append(result, i)
-	}
-	return result
-}
-
 func UniqueIntSlice(slice []int) []int {
 	keys := make(map[int]bool)
 	var list []int
@@ -49,6 +31,15 @@ func IsIntSliceContains(slice []int, item int) bool {
 	return false
 }
 
+func IsStringSliceContains(slice []string, item string) bool {
+	for _, sliceItem := range slice {
+		if sliceItem == item {
+			return true
+		}
+	}
+	return false
+}
+
 // FillPrefixWithRune fills the postfix of the input string with the rune.
 // input is the string to be filled.
 // length is the length of the output string (input + postfix fill).
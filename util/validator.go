@@ -6,26 +6,32 @@ import (
 	"strings"
 )
 
-// support partial input
-func IsValidPortList(portList string) bool {
-	portList = strings.ReplaceAll(portList, " ", "")
-
-	if portList == "" {
-		return true
-	}
-
-	// regex of valid port numbers (comma-sep)
-	portListRegex := regexp.MustCompile(`^(\d{1,5})(,(\d{1,5}))*$`)
-
-	return portListRegex.MatchString(portList)
-}
-
 func IsValidIPv4(input string) bool {
 	ipv4Regex := regexp.MustCompile(`^((25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)(\.|$)){4}$`)
 
 	return ipv4Regex.MatchString(input)
 }
 
+// hostname with labels of letter, digit, hyphen and underscore (e.g. db.internal.example),
+// the last label can not be numeric to avoid mistaken ip address (e.g. 10.0.0)
+func IsValidHostname(input string) bool {
+	input = strings.TrimSuffix(input, ".")
+	if input == "" || len(input) > 253 {
+		return false
+	}
+
+	labelRegex := regexp.MustCompile(`^[a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?$`)
+	labels := strings.Split(input, ".")
+	for _, label := range labels {
+		if !labelRegex.MatchString(label) {
+			return false
+		}
+	}
+
+	numericRegex := regexp.MustCompile(`^[0-9]+$`)
+	return !numericRegex.MatchString(labels[len(labels)-1])
+}
+
 func IsStruct(v interface{}) (ok bool, val reflect.Value) {
 	structVal := reflect.ValueOf(v)
 	kind := structVal.Kind()